@@ -40,11 +40,7 @@ func init() {
 // If errors is custom and there is "trace" key in context, it will be ignored for outputError
 func Failure(ctx echo.Context, status int, err error) error {
 	// set trace ID to response
-	traceID := trace.Get(Context(ctx))
-	if traceID != "" {
-		ctx.Response().Header().Set(TraceKey, traceID)
-		ctx.Response().Header().Set("X-Request-ID", traceID)
-	}
+	traceID := setTraceHeader(ctx)
 
 	// print error log
 	log.
@@ -92,11 +88,7 @@ func Error(ctx echo.Context, err error) error {
 // If body is not provided, will be returned empty string
 func Success(ctx echo.Context, status int, body ...any) error {
 	// set trace ID
-	traceID := trace.Get(Context(ctx))
-	if traceID != "" {
-		ctx.Response().Header().Set(TraceKey, traceID)
-		ctx.Response().Header().Set("X-Request-ID", traceID)
-	}
+	traceID := setTraceHeader(ctx)
 
 	// print success response log
 	log.
@@ -135,10 +127,13 @@ func SuccessRaw(ctx echo.Context, status int, body []byte, contentType ...string
 	return ctx.Blob(status, cType, body)
 }
 
-// ReturnExcel returns response with Excel file content type
+// ReturnExcel returns response with Excel file content type.
+//
+// Response is built by [Download], so it supports ranges & conditional requests
 func ReturnExcel(ctx echo.Context, name string, file []byte) error {
-	ctx.Response().Header().Set("Content-Disposition", "attachment; filename="+name)
-	return ctx.Blob(http.StatusOK, httpx.ContentTypeExcel, file)
+	return Download(ctx, name, file, DownloadConfig{
+		ContentType: httpx.ContentTypeExcel,
+	})
 }
 
 // Ok is wrap function over [Success] function.
@@ -171,6 +166,17 @@ func Created(ctx echo.Context, body ...any) error {
 	}
 }
 
+// setTraceHeader sets trace id from request context to the response headers and returns it
+func setTraceHeader(ctx echo.Context) string {
+	traceID := trace.Get(Context(ctx))
+	if traceID != "" {
+		ctx.Response().Header().Set(TraceKey, traceID)
+		ctx.Response().Header().Set(echo.HeaderXRequestID, traceID)
+	}
+
+	return traceID
+}
+
 func isPrimitive(object any) bool {
 	switch reflect.TypeOf(object).Kind() {
 	case reflect.Ptr, reflect.Struct, reflect.Interface,
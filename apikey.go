@@ -0,0 +1,301 @@
+package echox
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	apiKeyLength               = 32
+	defaultAPIKeyTouchInterval = time.Minute
+	apiKeyTouchQueueSize       = 1024
+	apiKeyTouchTimeout         = 5 * time.Second
+)
+
+// APIKey is stored API key. Keys are never stored in plain text, only their hashes created by [HashAPIKey].
+type APIKey struct {
+	// ID is public key identifier (for logs, revoking, etc.)
+	ID string `json:"id"`
+	// Hash is hex encoded SHA-256 hash of the key
+	Hash string `json:"hash"`
+	// Subject is caller of the key, e.g. service name
+	Subject string `json:"subject"`
+	// Scopes are granted scopes
+	Scopes []string `json:"scopes,omitempty"`
+	// Roles are caller roles
+	Roles []string `json:"roles,omitempty"`
+	// ExpiresAt is key expiration time. Zero time means key never expires
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// Disabled keys are rejected
+	Disabled bool `json:"disabled,omitempty"`
+	// LastUsedAt is time of the last request with the key
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+func (key APIKey) principal() Principal {
+	return Principal{
+		Subject: key.Subject,
+		Scopes:  key.Scopes,
+		Roles:   key.Roles,
+		Source:  PrincipalSourceAPIKey,
+		Attributes: map[string]any{
+			"key_id": key.ID,
+		},
+	}
+}
+
+// APIKeyStore keeps API keys. Implementations must be safe for concurrent use.
+type APIKeyStore interface {
+	// Lookup returns key by its hash. Returns false if there is no such key
+	Lookup(ctx context.Context, hash string) (APIKey, bool, error)
+	// Touch saves time of the last request with the key
+	Touch(ctx context.Context, hash string, usedAt time.Time) error
+}
+
+// HashAPIKey returns hash of the key which is kept in [APIKeyStore]
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateAPIKey generates random API key with provided prefix (e.g. "sk_live_") and returns it with its hash.
+// Key must be shown to the caller once, only hash should be saved
+func GenerateAPIKey(prefix string) (key, hash string, err error) {
+	secret := make([]byte, apiKeyLength)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	key = prefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, HashAPIKey(key), nil
+}
+
+// APIKeyConfig describes [APIKeyMiddleware].
+type APIKeyConfig struct {
+	// Header with API key. Default is "X-API-Key"
+	Header string
+	// Query is query param with API key (if key is not in header)
+	Query string
+	// Cookie is cookie with API key (if key is not in header or query)
+	Cookie string
+	// Scopes are required key scopes. Missing scopes cause 403
+	Scopes []string
+	// TouchInterval is how often last used time of one key is saved. Default is 1 minute
+	TouchInterval time.Duration
+}
+
+func newAPIKeyConfig(cfg ...APIKeyConfig) APIKeyConfig {
+	var config APIKeyConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.Header == "" {
+		config.Header = defaultAPIKeyHeader
+	}
+
+	if config.TouchInterval <= 0 {
+		config.TouchInterval = defaultAPIKeyTouchInterval
+	}
+
+	return config
+}
+
+// APIKeyMiddleware authenticates requests by API keys from header ("X-API-Key" by default), query param or cookie.
+//
+// Key is looked up in the store by its hash. Missing, unknown, disabled & expired keys get 401 Unauthorized,
+// keys without required scopes get 403 Forbidden. Caller is available by [CurrentPrincipal] function.
+//
+// Last used time is saved in background, so slow store does not slow down requests
+func APIKeyMiddleware(store APIKeyStore, cfg ...APIKeyConfig) echo.MiddlewareFunc {
+	config := newAPIKeyConfig(cfg...)
+	toucher := newAPIKeyToucher(store, config.TouchInterval)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			raw := apiKeyValue(ctx, config)
+			if raw == "" {
+				return Error(ctx, ErrAPIKeyMissing)
+			}
+
+			hash := HashAPIKey(raw)
+			key, ok, err := store.Lookup(Context(ctx), hash)
+			if err != nil {
+				return Error(ctx, ErrAPIKeyStore.SetError(err))
+			}
+
+			now := time.Now()
+			switch {
+			case !ok, key.Disabled:
+				return Error(ctx, ErrAPIKeyInvalid)
+			case !key.ExpiresAt.IsZero() && now.After(key.ExpiresAt):
+				return Error(ctx, newAPIKeyError(ErrAPIKeyExpired, key.ID))
+			}
+
+			if missing := missingScopes(key.Scopes, config.Scopes); len(missing) > 0 {
+				return Error(ctx, newScopeError(missing))
+			}
+
+			toucher.touch(hash, now)
+			SetPrincipal(ctx, key.principal())
+			return next(ctx)
+		}
+	}
+}
+
+func apiKeyValue(ctx echo.Context, config APIKeyConfig) string {
+	if key := ctx.Request().Header.Get(config.Header); key != "" {
+		return key
+	}
+
+	if config.Query != "" {
+		if key := ctx.QueryParam(config.Query); key != "" {
+			return key
+		}
+	}
+
+	if config.Cookie != "" {
+		if cookie, err := ctx.Cookie(config.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
+
+// apiKeyToucher saves last used time of the keys in background not often than once per interval for each key.
+// Background goroutine runs only while there are times to save, so middleware does not leak it
+type apiKeyToucher struct {
+	store     APIKeyStore
+	interval  time.Duration
+	touchedAt map[string]time.Time
+	queue     chan apiKeyTouch
+	running   bool
+	mx        sync.Mutex
+}
+
+type apiKeyTouch struct {
+	hash   string
+	usedAt time.Time
+}
+
+func newAPIKeyToucher(store APIKeyStore, interval time.Duration) *apiKeyToucher {
+	return &apiKeyToucher{
+		store:     store,
+		interval:  interval,
+		touchedAt: make(map[string]time.Time),
+		queue:     make(chan apiKeyTouch, apiKeyTouchQueueSize),
+	}
+}
+
+func (toucher *apiKeyToucher) touch(hash string, usedAt time.Time) {
+	toucher.mx.Lock()
+	defer toucher.mx.Unlock()
+
+	if usedAt.Sub(toucher.touchedAt[hash]) < toucher.interval {
+		return
+	}
+	toucher.touchedAt[hash] = usedAt
+
+	select {
+	case toucher.queue <- apiKeyTouch{hash: hash, usedAt: usedAt}:
+	default:
+		// store is too slow, last used time is not critical
+		log.Warn().Str("hash", hash).Msg("API key last used time is skipped: queue is full")
+	}
+
+	if !toucher.running {
+		toucher.running = true
+		go toucher.run()
+	}
+}
+
+// run saves queued times and stops when queue is empty. Queue is checked under lock,
+// so touch either sees running goroutine or starts new one
+func (toucher *apiKeyToucher) run() {
+	for {
+		select {
+		case touch := <-toucher.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), apiKeyTouchTimeout)
+			if err := toucher.store.Touch(ctx, touch.hash, touch.usedAt); err != nil {
+				log.Error().Err(err).Str("hash", touch.hash).Msg("Save API key last used time")
+			}
+			cancel()
+		default:
+			toucher.mx.Lock()
+			if len(toucher.queue) == 0 {
+				toucher.running = false
+				toucher.mx.Unlock()
+				return
+			}
+			toucher.mx.Unlock()
+		}
+	}
+}
+
+// MemoryAPIKeyStore is in-memory [APIKeyStore].
+type MemoryAPIKeyStore struct {
+	keys map[string]APIKey
+	mx   sync.RWMutex
+}
+
+// NewMemoryAPIKeyStore creates [MemoryAPIKeyStore] with provided keys
+func NewMemoryAPIKeyStore(keys ...APIKey) *MemoryAPIKeyStore {
+	store := &MemoryAPIKeyStore{
+		keys: make(map[string]APIKey, len(keys)),
+	}
+
+	for _, key := range keys {
+		store.keys[key.Hash] = key
+	}
+
+	return store
+}
+
+// Add adds or replaces key
+func (store *MemoryAPIKeyStore) Add(key APIKey) {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	store.keys[key.Hash] = key
+}
+
+// Revoke removes key by its ID
+func (store *MemoryAPIKeyStore) Revoke(id string) {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	for hash, key := range store.keys {
+		if key.ID == id {
+			delete(store.keys, hash)
+		}
+	}
+}
+
+func (store *MemoryAPIKeyStore) Lookup(_ context.Context, hash string) (APIKey, bool, error) {
+	store.mx.RLock()
+	defer store.mx.RUnlock()
+
+	key, ok := store.keys[hash]
+	return key, ok, nil
+}
+
+func (store *MemoryAPIKeyStore) Touch(_ context.Context, hash string, usedAt time.Time) error {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	if key, ok := store.keys[hash]; ok && usedAt.After(key.LastUsedAt) {
+		key.LastUsedAt = usedAt
+		store.keys[hash] = key
+	}
+
+	return nil
+}
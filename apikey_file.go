@@ -0,0 +1,205 @@
+package echox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/boostgo/log"
+)
+
+const (
+	fileAPIKeyCheckInterval = time.Second
+	fileAPIKeyUsageInterval = 10 * time.Second
+	fileAPIKeyUsageSuffix   = ".usage"
+)
+
+// FileAPIKeyStore is [APIKeyStore] which keeps keys in JSON file (array of [APIKey]).
+//
+// File is reloaded when it is changed, so keys could be added or revoked without restart.
+// Keys file is never written by the store: last used times are kept in "<path>.usage" file
+// (JSON object of times by key ID), which is saved not often than once per 10 seconds
+type FileAPIKeyStore struct {
+	path       string
+	usagePath  string
+	keys       map[string]APIKey
+	usage      map[string]time.Time
+	usageDirty bool
+	usageSaved time.Time
+	modTime    time.Time
+	checkedAt  time.Time
+	mx         sync.RWMutex
+}
+
+// NewFileAPIKeyStore creates [FileAPIKeyStore] and loads keys from provided file
+func NewFileAPIKeyStore(path string) (*FileAPIKeyStore, error) {
+	store := &FileAPIKeyStore{
+		path:      path,
+		usagePath: path + fileAPIKeyUsageSuffix,
+		keys:      make(map[string]APIKey),
+		usage:     make(map[string]time.Time),
+	}
+
+	if err := store.loadUsage(); err != nil {
+		return nil, err
+	}
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *FileAPIKeyStore) Lookup(_ context.Context, hash string) (APIKey, bool, error) {
+	store.reload()
+
+	store.mx.RLock()
+	defer store.mx.RUnlock()
+
+	key, ok := store.keys[hash]
+	if usedAt := store.usage[key.ID]; ok && usedAt.After(key.LastUsedAt) {
+		key.LastUsedAt = usedAt
+	}
+
+	return key, ok, nil
+}
+
+func (store *FileAPIKeyStore) Touch(_ context.Context, hash string, usedAt time.Time) error {
+	store.reload()
+
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	key, ok := store.keys[hash]
+	if !ok || !usedAt.After(store.usage[key.ID]) {
+		return nil
+	}
+
+	store.usage[key.ID] = usedAt
+	store.usageDirty = true
+	if time.Since(store.usageSaved) < fileAPIKeyUsageInterval {
+		return nil
+	}
+
+	return store.saveUsage()
+}
+
+// Flush saves last used times which are not saved yet. Call it on shutdown
+func (store *FileAPIKeyStore) Flush() error {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	if !store.usageDirty {
+		return nil
+	}
+
+	return store.saveUsage()
+}
+
+// reload loads keys if file was changed. File is checked not often than once a second.
+// If file could not be loaded, previous keys are kept
+func (store *FileAPIKeyStore) reload() {
+	store.mx.Lock()
+	if time.Since(store.checkedAt) < fileAPIKeyCheckInterval {
+		store.mx.Unlock()
+		return
+	}
+	store.checkedAt = time.Now()
+	modTime := store.modTime
+	store.mx.Unlock()
+
+	info, err := os.Stat(store.path)
+	if err != nil {
+		log.Error().Err(err).Str("file", store.path).Msg("Check API keys file")
+		return
+	}
+
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+
+	if err = store.load(); err != nil {
+		log.Error().Err(err).Str("file", store.path).Msg("Reload API keys file")
+	}
+}
+
+func (store *FileAPIKeyStore) load() error {
+	file, err := os.Open(store.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	list := make([]APIKey, 0)
+	if err = json.NewDecoder(file).Decode(&list); err != nil {
+		return err
+	}
+
+	keys := make(map[string]APIKey, len(list))
+	for _, key := range list {
+		keys[key.Hash] = key
+	}
+
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	store.keys = keys
+	store.modTime = info.ModTime()
+	store.checkedAt = time.Now()
+	return nil
+}
+
+func (store *FileAPIKeyStore) loadUsage() error {
+	blob, err := os.ReadFile(store.usagePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	return json.Unmarshal(blob, &store.usage)
+}
+
+// saveUsage writes last used times to temporary file and renames it, so readers never get partially written file.
+// Must be called under lock
+func (store *FileAPIKeyStore) saveUsage() error {
+	blob, err := json.MarshalIndent(store.usage, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(store.usagePath), filepath.Base(store.usagePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(blob); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), store.usagePath); err != nil {
+		return err
+	}
+
+	store.usageDirty = false
+	store.usageSaved = time.Now()
+	return nil
+}
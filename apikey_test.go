@@ -0,0 +1,189 @@
+package echox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, hash, err := GenerateAPIKey("sk_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key, "sk_test_") || hash != HashAPIKey(key) || len(hash) != 64 {
+		t.Fatalf("key = %q, hash = %q", key, hash)
+	}
+
+	if strings.Contains(hash, key) || HashAPIKey(key+"x") == hash {
+		t.Fatal("hash does not depend on key")
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	store := NewMemoryAPIKeyStore(
+		APIKey{ID: "reader", Hash: HashAPIKey("reader-key"), Subject: "billing", Scopes: []string{"orders:read"}},
+		APIKey{ID: "writer", Hash: HashAPIKey("writer-key"), Subject: "billing", Scopes: []string{"orders:write"}},
+		APIKey{ID: "disabled", Hash: HashAPIKey("disabled-key"), Scopes: []string{"orders:read"}, Disabled: true},
+		APIKey{ID: "expired", Hash: HashAPIKey("expired-key"), Scopes: []string{"orders:read"}, ExpiresAt: time.Now().Add(-time.Minute)},
+	)
+
+	handler := echo.New()
+	handler.GET("/orders", func(ctx echo.Context) error {
+		principal, _ := CurrentPrincipal(ctx)
+		return ctx.String(http.StatusOK, principal.Subject+" "+principal.Attributes["key_id"].(string))
+	}, APIKeyMiddleware(store, APIKeyConfig{Query: "api_key", Scopes: []string{"orders:read"}}))
+
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		status int
+		body   string
+	}{
+		{name: "header", header: "reader-key", status: http.StatusOK, body: "billing reader"},
+		{name: "query", query: "reader-key", status: http.StatusOK, body: "billing reader"},
+		{name: "missing", status: http.StatusUnauthorized},
+		{name: "unknown", header: "unknown-key", status: http.StatusUnauthorized},
+		{name: "disabled", header: "disabled-key", status: http.StatusUnauthorized},
+		{name: "expired", header: "expired-key", status: http.StatusUnauthorized, body: "api_key_expired"},
+		{name: "missing scope", header: "writer-key", status: http.StatusForbidden, body: "orders:read"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/orders?api_key="+tt.query, nil)
+			if tt.header != "" {
+				request.Header.Set(defaultAPIKeyHeader, tt.header)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+
+			if !strings.Contains(recorder.Body.String(), tt.body) {
+				t.Fatalf("body = %q, want %q", recorder.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func writeAPIKeyFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileAPIKeyStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	now := time.Now()
+	writeAPIKeyFile(t, path, `[{"id": "first", "hash": "`+HashAPIKey("first")+`"}]`, now.Add(-time.Hour))
+
+	store, err := NewFileAPIKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(raw string) bool {
+		t.Helper()
+
+		// skip reload interval
+		store.mx.Lock()
+		store.checkedAt = time.Time{}
+		store.mx.Unlock()
+
+		_, ok, err := store.Lookup(ctx, HashAPIKey(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return ok
+	}
+
+	if !lookup("first") {
+		t.Fatal("key from file is not found")
+	}
+
+	// changed file is reloaded
+	writeAPIKeyFile(t, path, `[{"id": "second", "hash": "`+HashAPIKey("second")+`"}]`, now.Add(-time.Minute))
+	if lookup("first") || !lookup("second") {
+		t.Fatal("keys file is not reloaded")
+	}
+
+	// broken file keeps previous keys
+	writeAPIKeyFile(t, path, `[{"id": `, now)
+	if !lookup("second") {
+		t.Fatal("previous keys are dropped on broken file")
+	}
+
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if !lookup("second") {
+		t.Fatal("previous keys are dropped on removed file")
+	}
+
+	// last used time is saved to sidecar file, not keys file
+	usedAt := now.Truncate(time.Second)
+	if err = store.Touch(ctx, HashAPIKey("second"), usedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("keys file is written: %v", err)
+	}
+
+	reloaded := &FileAPIKeyStore{usagePath: path + fileAPIKeyUsageSuffix}
+	if err = reloaded.loadUsage(); err != nil || !reloaded.usage["second"].Equal(usedAt) {
+		t.Fatalf("usage = %v, err = %v", reloaded.usage, err)
+	}
+}
+
+func TestAPIKeyToucherStops(t *testing.T) {
+	hash := HashAPIKey("key")
+	store := NewMemoryAPIKeyStore(APIKey{ID: "key", Hash: hash})
+	toucher := newAPIKeyToucher(store, time.Minute)
+
+	usedAt := time.Now()
+	toucher.touch(hash, usedAt)
+
+	// goroutine saves time and stops when queue is empty
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		toucher.mx.Lock()
+		running := toucher.running
+		toucher.mx.Unlock()
+
+		if !running {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("toucher goroutine is not stopped")
+		}
+	}
+
+	if key, _, _ := store.Lookup(context.Background(), hash); !key.LastUsedAt.Equal(usedAt) {
+		t.Fatalf("last used at = %v, want %v", key.LastUsedAt, usedAt)
+	}
+}
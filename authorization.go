@@ -0,0 +1,282 @@
+package echox
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AccessRequest is authorization request passed to [PolicyEngine].
+type AccessRequest struct {
+	// Principal is authenticated caller
+	Principal Principal
+	// Permissions are required permissions, e.g. "orders:read"
+	Permissions []string
+	// Any means that any of permissions is enough, otherwise all of them are required
+	Any bool
+	// Method is request method
+	Method string
+	// Route is route path template, e.g. "/orders/:id"
+	Route string
+	// Params are route path params, e.g. {"id": "42"}
+	Params map[string]string
+}
+
+// AccessDecision is [PolicyEngine] result.
+type AccessDecision struct {
+	// Allowed is true if access is granted
+	Allowed bool
+	// Missing are required permissions which are not granted
+	Missing []string
+}
+
+// PolicyEngine decides if caller has access to the route.
+type PolicyEngine interface {
+	Authorize(ctx context.Context, request AccessRequest) (AccessDecision, error)
+}
+
+// PolicyEngineFunc is function implementation of [PolicyEngine]
+type PolicyEngineFunc func(ctx context.Context, request AccessRequest) (AccessDecision, error)
+
+func (fn PolicyEngineFunc) Authorize(ctx context.Context, request AccessRequest) (AccessDecision, error) {
+	return fn(ctx, request)
+}
+
+var (
+	_policyEngine   PolicyEngine = NewRBACEngine()
+	_policyEngineMx sync.RWMutex
+)
+
+// RegisterPolicyEngine sets engine used by [Require] & [RequireAny].
+// Default is [RBACEngine] without roles, which grants principal scopes only
+func RegisterPolicyEngine(engine PolicyEngine) {
+	if engine == nil {
+		return
+	}
+
+	_policyEngineMx.Lock()
+	defer _policyEngineMx.Unlock()
+
+	_policyEngine = engine
+}
+
+func policyEngine() PolicyEngine {
+	_policyEngineMx.RLock()
+	defer _policyEngineMx.RUnlock()
+
+	return _policyEngine
+}
+
+// Require allows requests only if caller has all provided permissions.
+// Could be used as route or group middleware after auth middleware ([JWTMiddleware], [APIKeyMiddleware]).
+//
+// Requests without principal get 401 Unauthorized, requests without permissions get 403 Forbidden
+// with missing permissions in failure context. Panics if no permissions provided
+func Require(permissions ...string) echo.MiddlewareFunc {
+	return authorizationMiddleware(permissions, false)
+}
+
+// RequireAny allows requests only if caller has any of provided permissions. Panics if no permissions provided
+func RequireAny(permissions ...string) echo.MiddlewareFunc {
+	return authorizationMiddleware(permissions, true)
+}
+
+func authorizationMiddleware(permissions []string, anyOf bool) echo.MiddlewareFunc {
+	// empty list would allow everyone, it is always a mistake in routes setup
+	if len(permissions) == 0 {
+		panic("echox: at least one permission must be required")
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			principal, ok := CurrentPrincipal(ctx)
+			if !ok {
+				return Error(ctx, ErrUnauthenticated)
+			}
+
+			request := AccessRequest{
+				Principal:   principal,
+				Permissions: permissions,
+				Any:         anyOf,
+				Method:      ctx.Request().Method,
+				Route:       ctx.Path(),
+				Params:      routeParams(ctx),
+			}
+
+			decision, err := policyEngine().Authorize(Context(ctx), request)
+			if err != nil {
+				return Error(ctx, ErrAuthorization.SetError(err))
+			}
+
+			if !decision.Allowed {
+				return Error(ctx, newPermissionDeniedError(decision.Missing, anyOf))
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+func routeParams(ctx echo.Context) map[string]string {
+	names, values := ctx.ParamNames(), ctx.ParamValues()
+	params := make(map[string]string, len(names))
+	for index, name := range names {
+		if index < len(values) {
+			params[name] = values[index]
+		}
+	}
+
+	return params
+}
+
+// ResourceRule grants permission for the concrete resource, e.g. if caller is owner of ":id".
+type ResourceRule func(ctx context.Context, request AccessRequest) (bool, error)
+
+// SubjectParam grants permission if route param is caller subject, e.g. "/users/:id" for user with the same ID
+func SubjectParam(param string) ResourceRule {
+	return func(_ context.Context, request AccessRequest) (bool, error) {
+		value, ok := request.Params[param]
+		return ok && value != "" && value == request.Principal.Subject, nil
+	}
+}
+
+// Owner grants permission if caller subject is owner of the resource with ID from route param.
+// Owner function returns owner subject of the resource
+func Owner(param string, owner func(ctx context.Context, resourceID string) (string, error)) ResourceRule {
+	return func(ctx context.Context, request AccessRequest) (bool, error) {
+		resourceID, ok := request.Params[param]
+		if !ok || resourceID == "" {
+			return false, nil
+		}
+
+		subject, err := owner(ctx, resourceID)
+		if err != nil {
+			return false, err
+		}
+
+		return subject != "" && subject == request.Principal.Subject, nil
+	}
+}
+
+// RBACEngine is role based [PolicyEngine].
+//
+// Caller permissions are its scopes & permissions of its roles. Role permission "*" grants everything,
+// role permission "orders:*" grants every "orders:" permission. Scopes grant only exactly the same permissions,
+// so token could not get wildcard access by its scopes.
+// Permissions not granted by roles could be granted for concrete resource by [ResourceRule]
+type RBACEngine struct {
+	roles     map[string][]string
+	resources map[string][]ResourceRule
+	mx        sync.RWMutex
+}
+
+// NewRBACEngine creates [RBACEngine]
+func NewRBACEngine() *RBACEngine {
+	return &RBACEngine{
+		roles:     make(map[string][]string),
+		resources: make(map[string][]ResourceRule),
+	}
+}
+
+// Role grants permissions to the role
+func (engine *RBACEngine) Role(role string, permissions ...string) *RBACEngine {
+	engine.mx.Lock()
+	defer engine.mx.Unlock()
+
+	engine.roles[role] = append(engine.roles[role], permissions...)
+	return engine
+}
+
+// Resource adds rules which grant permission for the concrete resource
+func (engine *RBACEngine) Resource(permission string, rules ...ResourceRule) *RBACEngine {
+	engine.mx.Lock()
+	defer engine.mx.Unlock()
+
+	engine.resources[permission] = append(engine.resources[permission], rules...)
+	return engine
+}
+
+func (engine *RBACEngine) Authorize(ctx context.Context, request AccessRequest) (AccessDecision, error) {
+	// nothing required is not a reason to allow everyone
+	if len(request.Permissions) == 0 {
+		return AccessDecision{}, nil
+	}
+
+	rolePermissions := engine.rolePermissions(request.Principal)
+
+	missing := make([]string, 0)
+	for _, permission := range request.Permissions {
+		ok, err := engine.granted(ctx, request, rolePermissions, permission)
+		if err != nil {
+			return AccessDecision{}, err
+		}
+
+		if ok && request.Any {
+			return AccessDecision{Allowed: true}, nil
+		}
+
+		if !ok {
+			missing = append(missing, permission)
+		}
+	}
+
+	return AccessDecision{
+		Allowed: len(missing) == 0,
+		Missing: missing,
+	}, nil
+}
+
+// rolePermissions returns permissions of principal roles
+func (engine *RBACEngine) rolePermissions(principal Principal) []string {
+	engine.mx.RLock()
+	defer engine.mx.RUnlock()
+
+	permissions := make([]string, 0)
+	for _, role := range principal.Roles {
+		permissions = append(permissions, engine.roles[role]...)
+	}
+
+	return permissions
+}
+
+func (engine *RBACEngine) granted(
+	ctx context.Context,
+	request AccessRequest,
+	rolePermissions []string,
+	permission string,
+) (bool, error) {
+	if request.Principal.HasScope(permission) {
+		return true, nil
+	}
+
+	for _, grant := range rolePermissions {
+		if matchPermission(grant, permission) {
+			return true, nil
+		}
+	}
+
+	engine.mx.RLock()
+	rules := engine.resources[permission]
+	engine.mx.RUnlock()
+
+	for _, rule := range rules {
+		ok, err := rule(ctx, request)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// matchPermission checks if grant ("orders:read", "orders:*" or "*") covers permission
+func matchPermission(grant, permission string) bool {
+	if grant == "*" || grant == permission {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(grant, "*")
+	return ok && strings.HasPrefix(permission, prefix)
+}
@@ -0,0 +1,126 @@
+package echox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerPolicyEngine(t *testing.T, engine PolicyEngine) {
+	t.Helper()
+
+	previous := policyEngine()
+	RegisterPolicyEngine(engine)
+	t.Cleanup(func() {
+		RegisterPolicyEngine(previous)
+	})
+}
+
+// principalMiddleware sets principal from "X-Subject", "X-Scopes" & "X-Roles" headers
+func principalMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		header := ctx.Request().Header
+		if header.Get("X-Subject") != "" {
+			SetPrincipal(ctx, Principal{
+				Subject: header.Get("X-Subject"),
+				Scopes:  strings.Fields(header.Get("X-Scopes")),
+				Roles:   strings.Fields(header.Get("X-Roles")),
+			})
+		}
+
+		return next(ctx)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	owners := map[string]string{"1": "alice", "2": "bob"}
+	registerPolicyEngine(t, NewRBACEngine().
+		Role("admin", "*").
+		Role("support", "orders:*").
+		Resource("orders:read", Owner("id", func(_ context.Context, id string) (string, error) {
+			if id == "broken" {
+				return "", errors.New("owner storage is down")
+			}
+
+			return owners[id], nil
+		})).
+		Resource("users:write", SubjectParam("id")))
+
+	handler := echo.New()
+	handler.Use(principalMiddleware)
+	ok := func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}
+	handler.GET("/orders/:id", ok, Require("orders:read"))
+	handler.DELETE("/orders/:id", ok, Require("orders:read", "orders:delete"))
+	handler.PATCH("/orders/:id", ok, RequireAny("orders:write", "orders:admin"))
+	handler.PUT("/users/:id", ok, Require("users:write"))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		subject string
+		scopes  string
+		roles   string
+		status  int
+		missing string
+	}{
+		{name: "no principal", method: http.MethodGet, path: "/orders/1", status: http.StatusUnauthorized},
+		{name: "exact scope", method: http.MethodGet, path: "/orders/2", subject: "carol", scopes: "orders:read", status: http.StatusOK},
+		{name: "wildcard scope is not expanded", method: http.MethodGet, path: "/orders/2", subject: "carol", scopes: "orders:*", status: http.StatusForbidden, missing: `["orders:read"]`},
+		{name: "role wildcard", method: http.MethodDelete, path: "/orders/2", subject: "carol", roles: "support", status: http.StatusOK},
+		{name: "role any", method: http.MethodDelete, path: "/orders/2", subject: "carol", roles: "admin", status: http.StatusOK},
+		{name: "require all", method: http.MethodDelete, path: "/orders/2", subject: "carol", scopes: "orders:read", status: http.StatusForbidden, missing: `["orders:delete"]`},
+		{name: "require any", method: http.MethodPatch, path: "/orders/2", subject: "carol", scopes: "orders:admin", status: http.StatusOK},
+		{name: "require any missing", method: http.MethodPatch, path: "/orders/2", subject: "carol", scopes: "orders:read", status: http.StatusForbidden, missing: `["orders:write","orders:admin"]`},
+		{name: "owner", method: http.MethodGet, path: "/orders/1", subject: "alice", status: http.StatusOK},
+		{name: "not owner", method: http.MethodGet, path: "/orders/2", subject: "alice", status: http.StatusForbidden, missing: `["orders:read"]`},
+		{name: "owner lookup error", method: http.MethodGet, path: "/orders/broken", subject: "alice", status: http.StatusInternalServerError},
+		{name: "subject param", method: http.MethodPut, path: "/users/alice", subject: "alice", status: http.StatusOK},
+		{name: "other subject param", method: http.MethodPut, path: "/users/bob", subject: "alice", status: http.StatusForbidden, missing: `["users:write"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			request.Header.Set("X-Subject", tt.subject)
+			request.Header.Set("X-Scopes", tt.scopes)
+			request.Header.Set("X-Roles", tt.roles)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+
+			if tt.missing != "" && !strings.Contains(recorder.Body.String(), `"missing":`+tt.missing) {
+				t.Fatalf("body = %s, want missing %s", recorder.Body.String(), tt.missing)
+			}
+		})
+	}
+}
+
+func TestRequireEmptyPermissions(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Require without permissions does not panic")
+		}
+	}()
+
+	Require()
+}
+
+func TestRBACEngineEmptyPermissions(t *testing.T) {
+	decision, err := NewRBACEngine().Role("admin", "*").Authorize(context.Background(), AccessRequest{
+		Principal: Principal{Subject: "alice", Roles: []string{"admin"}},
+	})
+	if err != nil || decision.Allowed {
+		t.Fatalf("decision = %+v, err = %v", decision, err)
+	}
+}
@@ -0,0 +1,216 @@
+package echox
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	bodyBufferKey = "request-body-buffer"
+
+	defaultBodyMaxSize         = 10 << 20 // 10MB
+	defaultBodyMemoryThreshold = 1 << 20  // 1MB
+)
+
+// BodyBufferConfig describes how [BodyBufferMiddleware] buffers request body.
+type BodyBufferConfig struct {
+	// MaxSize is maximum request body size in bytes. Bigger bodies are rejected with 413 status.
+	//
+	// Default is 10MB
+	MaxSize int64
+	// MemoryThreshold is body size in bytes after which body is spilled to temporary file.
+	//
+	// Default is 1MB
+	MemoryThreshold int64
+	// TempDir is directory for spilled bodies. Default is [os.TempDir]
+	TempDir string
+}
+
+func newBodyBufferConfig(cfg ...BodyBufferConfig) BodyBufferConfig {
+	var config BodyBufferConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultBodyMaxSize
+	}
+
+	if config.MemoryThreshold <= 0 {
+		config.MemoryThreshold = defaultBodyMemoryThreshold
+	}
+
+	if config.MemoryThreshold > config.MaxSize {
+		config.MemoryThreshold = config.MaxSize
+	}
+
+	return config
+}
+
+// bufferedBody is re-readable request body kept in memory or in temporary file.
+type bufferedBody struct {
+	reader io.ReadSeeker
+	file   *os.File
+}
+
+func (body *bufferedBody) Read(p []byte) (int, error) {
+	return body.reader.Read(p)
+}
+
+// Close does nothing: body stays readable till [BodyBufferMiddleware] releases it
+func (body *bufferedBody) Close() error {
+	return nil
+}
+
+func (body *bufferedBody) rewind() error {
+	_, err := body.reader.Seek(0, io.SeekStart)
+	return err
+}
+
+func (body *bufferedBody) release() {
+	if body.file == nil {
+		return
+	}
+
+	_ = body.file.Close()
+	_ = os.Remove(body.file.Name())
+}
+
+// BodyBufferMiddleware reads request body once and keeps it in memory (or in temporary file
+// if body is bigger than memory threshold), so [Body], [Parse] and other middlewares could read the same body.
+//
+// Bodies bigger than max size are rejected with [ErrRequestBodyTooLarge] (413 status)
+func BodyBufferMiddleware(cfg ...BodyBufferConfig) echo.MiddlewareFunc {
+	config := newBodyBufferConfig(cfg...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+			if request.Body == nil || request.Body == http.NoBody {
+				return next(ctx)
+			}
+
+			if request.ContentLength > config.MaxSize {
+				return Error(ctx, newRequestBodyTooLargeError(config.MaxSize))
+			}
+
+			body, err := bufferBody(request.Body, config)
+			if err != nil {
+				return Error(ctx, err)
+			}
+			defer body.release()
+
+			_ = request.Body.Close()
+			request.Body = body
+			Set(ctx, bodyBufferKey, body)
+
+			return next(ctx)
+		}
+	}
+}
+
+// RewindBody moves buffered request body to the beginning, so it could be read one more time.
+//
+// Works only if [BodyBufferMiddleware] is set, otherwise does nothing
+func RewindBody(ctx echo.Context) error {
+	body, ok := requestBodyBuffer(ctx)
+	if !ok {
+		return nil
+	}
+
+	if err := body.rewind(); err != nil {
+		return ErrBufferRequestBody.SetError(err)
+	}
+
+	ctx.Request().Body = body
+	return nil
+}
+
+func requestBodyBuffer(ctx echo.Context) (*bufferedBody, bool) {
+	body, ok := Context(ctx).Value(bodyBufferKey).(*bufferedBody)
+	return body, ok
+}
+
+func bufferBody(source io.Reader, config BodyBufferConfig) (*bufferedBody, error) {
+	limited := &io.LimitedReader{R: source, N: config.MaxSize + 1}
+
+	// read body to memory till threshold
+	var memory bytes.Buffer
+	if _, err := io.Copy(&memory, io.LimitReader(limited, config.MemoryThreshold+1)); err != nil {
+		return nil, wrapError(ErrReadRequestBody, err)
+	}
+
+	size := int64(memory.Len())
+	if size > config.MaxSize {
+		return nil, newRequestBodyTooLargeError(config.MaxSize)
+	}
+
+	if size <= config.MemoryThreshold {
+		return &bufferedBody{
+			reader: bytes.NewReader(memory.Bytes()),
+		}, nil
+	}
+
+	// spill body to temporary file
+	file, err := os.CreateTemp(config.TempDir, "echox-body-*")
+	if err != nil {
+		return nil, ErrBufferRequestBody.SetError(err)
+	}
+
+	body := &bufferedBody{
+		reader: file,
+		file:   file,
+	}
+
+	written, err := io.Copy(file, io.MultiReader(&memory, limited))
+	if err != nil {
+		body.release()
+		return nil, wrapError(ErrReadRequestBody, err)
+	}
+
+	if written > config.MaxSize {
+		body.release()
+		return nil, newRequestBodyTooLargeError(config.MaxSize)
+	}
+
+	if err = body.rewind(); err != nil {
+		body.release()
+		return nil, ErrBufferRequestBody.SetError(err)
+	}
+
+	return body, nil
+}
+
+// peekBody reads request body and leaves it readable for the next readers.
+// Bodies bigger than provided limit are rejected with [ErrRequestBodyTooLarge]
+func peekBody(ctx echo.Context, limit int64) ([]byte, error) {
+	if _, ok := requestBodyBuffer(ctx); ok {
+		return Body(ctx)
+	}
+
+	request := ctx.Request()
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if request.ContentLength > limit {
+		return nil, newRequestBodyTooLargeError(limit)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(request.Body, limit+1))
+	if err != nil {
+		return nil, wrapError(ErrReadRequestBody, err)
+	}
+
+	if int64(len(body)) > limit {
+		return nil, newRequestBodyTooLargeError(limit)
+	}
+
+	_ = request.Body.Close()
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
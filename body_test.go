@@ -0,0 +1,74 @@
+package echox
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/boostgo/httpx"
+	"github.com/labstack/echo/v4"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestWrapErrorKeepsStatus(t *testing.T) {
+	err := wrapError(ErrReadRequestBody, io.ErrUnexpectedEOF)
+	if status := httpx.StatusCodeByError(err); status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("cause is lost")
+	}
+}
+
+func TestBodyBufferMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   io.Reader
+		config BodyBufferConfig
+		status int
+	}{
+		{"memory", strings.NewReader("hello"), BodyBufferConfig{}, http.StatusOK},
+		{"temp file", strings.NewReader("hello"), BodyBufferConfig{MemoryThreshold: 2}, http.StatusOK},
+		{"too large", strings.NewReader("hello"), BodyBufferConfig{MaxSize: 2}, http.StatusRequestEntityTooLarge},
+		{"read error", failingReader{}, BodyBufferConfig{}, http.StatusBadRequest},
+		{"read error after threshold", io.MultiReader(strings.NewReader("hello"), failingReader{}),
+			BodyBufferConfig{MemoryThreshold: 2}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := BodyBufferMiddleware(tt.config)(func(ctx echo.Context) error {
+				for range 2 {
+					body, err := Body(ctx)
+					if err != nil {
+						return err
+					}
+
+					if string(body) != "hello" {
+						t.Errorf("body = %q", body)
+					}
+				}
+
+				return ctx.NoContent(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(tt.body))
+			request.ContentLength = -1
+			recorder := httptest.NewRecorder()
+			_ = handler(echo.New().NewContext(request, recorder))
+
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+		})
+	}
+}
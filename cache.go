@@ -0,0 +1,447 @@
+package echox
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boostgo/errorx"
+	"github.com/boostgo/httpx"
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	CacheHeader = "X-Cache"
+
+	CacheHit    = "HIT"
+	CacheMiss   = "MISS"
+	CacheBypass = "BYPASS"
+
+	ageHeader = "Age"
+)
+
+// cacheSkipHeaders are response headers which are not saved to cache
+var cacheSkipHeaders = []string{
+	"Set-Cookie", "Connection", "Keep-Alive", "Transfer-Encoding", "Upgrade",
+	"Proxy-Authenticate", "Trailer", ageHeader, CacheHeader, TraceKey, echo.HeaderXRequestID,
+}
+
+// CacheConfig describes [CacheMiddleware].
+type CacheConfig struct {
+	// Statuses are response status codes which could be cached. Default is 200
+	Statuses []int
+	// Methods are request methods which could be cached. Default are GET & HEAD.
+	// Responses of HEAD requests are never saved, but HEAD requests could get saved GET response
+	Methods []string
+	// IgnoreRequestDirectives ignores request "Cache-Control" & "Pragma" headers
+	IgnoreRequestDirectives bool
+}
+
+func newCacheConfig(cfg ...CacheConfig) CacheConfig {
+	var config CacheConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if len(config.Statuses) == 0 {
+		config.Statuses = []int{http.StatusOK}
+	}
+
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+
+	return config
+}
+
+// cacheEntry is cached response saved to distributor as JSON
+type cacheEntry struct {
+	Status    int               `json:"status"`
+	Header    http.Header       `json:"header"`
+	Body      []byte            `json:"body"`
+	StoredAt  time.Time         `json:"stored_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	Vary      map[string]string `json:"vary,omitempty"`
+}
+
+// matches checks request has the same values of "Vary" headers as request which response was cached
+func (entry *cacheEntry) matches(request *http.Request) bool {
+	for name, value := range entry.Vary {
+		if request.Header.Get(name) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (entry *cacheEntry) age(now time.Time) time.Duration {
+	return max(0, now.Sub(entry.StoredAt))
+}
+
+// CacheMiddleware caches responses in provided distributor for provided ttl.
+//
+// Only responses with configured statuses (200 by default) of GET requests are cached. Cached response
+// is replayed with its status & headers and "Age" & "X-Cache" headers.
+//
+// Request directives: "no-store" bypasses cache, "no-cache" and "max-age=0" refresh cached response,
+// "max-age=N" accepts responses not older than N seconds.
+//
+// Response directives: "no-store" & "private" responses (and responses with cookies or "Vary: *")
+// are not cached, "s-maxage" & "max-age" override ttl. Responses are cached per "Vary" header values.
+//
+// Concurrent misses of the same request are collapsed: only one request calls handler
+func CacheMiddleware(ttl time.Duration, distributor httpx.CacheDistributor, cfg ...CacheConfig) echo.MiddlewareFunc {
+	cache := &responseCache{
+		ttl:         ttl,
+		distributor: distributor,
+		config:      newCacheConfig(cfg...),
+		flights:     make(map[string]*cacheFlight),
+	}
+
+	registerCacheInvalidator(distributor)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+			if !slices.Contains(cache.config.Methods, request.Method) {
+				return next(ctx)
+			}
+
+			directives := parseCacheControl("")
+			if !cache.config.IgnoreRequestDirectives {
+				directives = parseCacheControl(request.Header.Get(echo.HeaderCacheControl))
+				if strings.Contains(strings.ToLower(request.Header.Get("Pragma")), "no-cache") {
+					directives.noCache = true
+				}
+			}
+
+			if directives.noStore {
+				ctx.Response().Header().Set(CacheHeader, CacheBypass)
+				return next(ctx)
+			}
+
+			if !directives.noCache && directives.maxAge != 0 {
+				if entry, ok := cache.load(ctx); ok && directives.accepts(entry) {
+					return replayCachedResponse(ctx, entry, CacheHit)
+				}
+			}
+
+			return cache.miss(ctx, next)
+		}
+	}
+}
+
+type responseCache struct {
+	ttl         time.Duration
+	distributor httpx.CacheDistributor
+	config      CacheConfig
+	flights     map[string]*cacheFlight
+	flightsMx   sync.Mutex
+}
+
+// cacheFlight is in-flight request of the leader. Entry is set before done is closed
+type cacheFlight struct {
+	done  chan struct{}
+	entry *cacheEntry
+}
+
+// load returns cached response of the request
+func (cache *responseCache) load(ctx echo.Context) (*cacheEntry, bool) {
+	blob, ok, err := cache.distributor.Get(Context(ctx), ctx.Request())
+	if err != nil {
+		if !errors.Is(err, errorx.ErrNotFound) {
+			logCacheError(ctx, err, "Get cache by HTTP distributor")
+		}
+
+		return nil, false
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err = json.Unmarshal(blob, &entry); err != nil || entry.Status == 0 {
+		// entry of other format (e.g. saved by previous versions)
+		return nil, false
+	}
+
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		return nil, false
+	}
+
+	if !entry.matches(ctx.Request()) {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// miss calls handler and saves its response. Concurrent requests wait for the first one and replay its response
+func (cache *responseCache) miss(ctx echo.Context, next echo.HandlerFunc) error {
+	request := ctx.Request()
+
+	// HEAD response has no body, so it could not be saved
+	if request.Method == http.MethodHead {
+		ctx.Response().Header().Set(CacheHeader, CacheMiss)
+		return next(ctx)
+	}
+
+	key := request.Method + " " + request.Host + request.URL.RequestURI()
+	if keyer, ok := cache.distributor.(CacheKeyer); ok {
+		key = keyer.Key(request)
+	}
+
+	cache.flightsMx.Lock()
+	flight, ok := cache.flights[key]
+	if !ok {
+		flight = &cacheFlight{
+			done: make(chan struct{}),
+		}
+		cache.flights[key] = flight
+	}
+	cache.flightsMx.Unlock()
+
+	if !ok {
+		return cache.lead(ctx, next, key, flight)
+	}
+
+	// wait for the leader till request is canceled
+	select {
+	case <-flight.done:
+	case <-Context(ctx).Done():
+		// client is gone, there is nobody to respond
+		return nil
+	}
+
+	// response of the leader request could not be shared with this request
+	entry := flight.entry
+	if entry == nil || !entry.matches(request) {
+		ctx.Response().Header().Set(CacheHeader, CacheMiss)
+		return next(ctx)
+	}
+
+	return replayCachedResponse(ctx, entry, CacheHit)
+}
+
+// lead calls handler in the current goroutine and shares its response with waiting requests.
+// Waiting requests are released even if handler panics
+func (cache *responseCache) lead(ctx echo.Context, next echo.HandlerFunc, key string, flight *cacheFlight) error {
+	defer func() {
+		cache.flightsMx.Lock()
+		delete(cache.flights, key)
+		cache.flightsMx.Unlock()
+
+		close(flight.done)
+	}()
+
+	entry, err := cache.handle(ctx, next)
+	flight.entry = entry
+	return err
+}
+
+// handle calls handler, saves its response if it is cacheable and returns saved entry
+func (cache *responseCache) handle(ctx echo.Context, next echo.HandlerFunc) (*cacheEntry, error) {
+	response := ctx.Response()
+	response.Header().Set(CacheHeader, CacheMiss)
+
+	recorder := newResponseRecorder(response.Writer)
+	response.Writer = recorder
+	defer func() {
+		response.Writer = recorder.inner
+	}()
+
+	tags := &cacheTagSet{}
+	Set(ctx, cacheTagsKey, tags)
+
+	if err := next(ctx); err != nil {
+		return nil, err
+	}
+
+	entry, ttl, ok := cache.entry(ctx, recorder)
+	if !ok {
+		return nil, nil
+	}
+
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		logCacheError(ctx, err, "Marshal cache entry")
+		return nil, nil
+	}
+
+	meta := CacheMeta{
+		Tags:  tags.list(),
+		Route: ctx.Request().Method + " " + ctx.Path(),
+	}
+
+	if setter, ok := cache.distributor.(CacheMetaSetter); ok {
+		err = setter.SetWithMeta(Context(ctx), ctx.Request(), blob, ttl, meta)
+	} else {
+		err = cache.distributor.Set(Context(ctx), ctx.Request(), blob, ttl)
+	}
+
+	if err != nil {
+		logCacheError(ctx, err, "Set cache by HTTP distributor")
+	}
+
+	return entry, nil
+}
+
+// entry creates cache entry from recorded response if response is cacheable
+func (cache *responseCache) entry(ctx echo.Context, recorder *responseRecorder) (*cacheEntry, time.Duration, bool) {
+	response := ctx.Response()
+	if !response.Committed || !slices.Contains(cache.config.Statuses, recorder.Status()) {
+		return nil, 0, false
+	}
+
+	header := response.Header()
+	directives := parseCacheControl(header.Get(echo.HeaderCacheControl))
+	if directives.noStore || directives.private || header.Get("Set-Cookie") != "" {
+		return nil, 0, false
+	}
+
+	ttl := cache.ttl
+	switch {
+	case directives.sMaxAge >= 0:
+		ttl = time.Duration(directives.sMaxAge) * time.Second
+	case directives.maxAge >= 0:
+		ttl = time.Duration(directives.maxAge) * time.Second
+	}
+
+	if ttl <= 0 {
+		return nil, 0, false
+	}
+
+	vary, ok := varyValues(ctx.Request(), header)
+	if !ok {
+		return nil, 0, false
+	}
+
+	now := time.Now()
+	entry := &cacheEntry{
+		Status:    recorder.Status(),
+		Header:    header.Clone(),
+		Body:      slices.Clone(recorder.body.Bytes()),
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+		Vary:      vary,
+	}
+
+	for _, name := range cacheSkipHeaders {
+		entry.Header.Del(name)
+	}
+
+	return entry, ttl, true
+}
+
+// varyValues returns request values of headers from response "Vary" header. Returns false for "Vary: *"
+func varyValues(request *http.Request, header http.Header) (map[string]string, bool) {
+	var values map[string]string
+	for _, vary := range header.Values(echo.HeaderVary) {
+		for _, name := range strings.Split(vary, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			switch name {
+			case "":
+				continue
+			case "*":
+				return nil, false
+			}
+
+			if values == nil {
+				values = make(map[string]string)
+			}
+
+			values[name] = request.Header.Get(name)
+		}
+	}
+
+	return values, true
+}
+
+func replayCachedResponse(ctx echo.Context, entry *cacheEntry, state string) error {
+	header := ctx.Response().Header()
+	for name, values := range entry.Header {
+		header[name] = slices.Clone(values)
+	}
+
+	setTraceHeader(ctx)
+	header.Set(ageHeader, strconv.FormatInt(int64(entry.age(time.Now())/time.Second), 10))
+	header.Set(CacheHeader, state)
+
+	ctx.Response().WriteHeader(entry.Status)
+	if ctx.Request().Method != http.MethodHead {
+		if _, err := ctx.Response().Write(entry.Body); err != nil {
+			return err
+		}
+	}
+
+	log.
+		Info().
+		Ctx(Context(ctx)).
+		Int("status", entry.Status).
+		Str("method", ctx.Request().Method).
+		Str("cache", state).
+		Msg(ctx.Request().RequestURI)
+
+	return nil
+}
+
+func logCacheError(ctx echo.Context, err error, message string) {
+	log.
+		Error().
+		Ctx(Context(ctx)).
+		Err(err).
+		Msg(message)
+}
+
+// cacheDirectives are parsed "Cache-Control" header. Missing ages are -1
+type cacheDirectives struct {
+	noStore bool
+	noCache bool
+	private bool
+	maxAge  int
+	sMaxAge int
+}
+
+// accepts checks cached response is not older than request "max-age"
+func (directives cacheDirectives) accepts(entry *cacheEntry) bool {
+	return directives.maxAge < 0 || entry.age(time.Now()) <= time.Duration(directives.maxAge)*time.Second
+}
+
+func parseCacheControl(value string) cacheDirectives {
+	directives := cacheDirectives{
+		maxAge:  -1,
+		sMaxAge: -1,
+	}
+
+	for _, directive := range strings.Split(value, ",") {
+		name, argument, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		argument = strings.Trim(strings.TrimSpace(argument), `"`)
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "no-store":
+			directives.noStore = true
+		case "no-cache":
+			directives.noCache = true
+		case "private":
+			directives.private = true
+		case "max-age":
+			if seconds, err := strconv.Atoi(argument); err == nil && seconds >= 0 {
+				directives.maxAge = seconds
+			}
+		case "s-maxage":
+			if seconds, err := strconv.Atoi(argument); err == nil && seconds >= 0 {
+				directives.sMaxAge = seconds
+			}
+		}
+	}
+
+	return directives
+}
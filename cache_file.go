@@ -0,0 +1,278 @@
+package echox
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	fileCacheExtension = ".cache"
+	fileCacheMaxHeader = 1 << 20 // max length of key & meta
+	fileCacheHeaderLen = 16      // expiration unix nano (8 bytes), key length (4 bytes) & meta length (4 bytes)
+)
+
+// FileCacheConfig describes [FileCacheDistributor].
+type FileCacheConfig struct {
+	// Key builds request cache key. Default is [DefaultCacheKey]
+	Key CacheKeyFunc
+}
+
+// FileCacheDistributor is [httpx.CacheDistributor] which keeps responses in files of local directory.
+// Suits large responses which should not be kept in memory.
+//
+// Every response is file named by key hash. Expired files are removed on read
+// or by [FileCacheDistributor.DeleteExpired]
+type FileCacheDistributor struct {
+	dir    string
+	config FileCacheConfig
+}
+
+// NewFileCacheDistributor creates [FileCacheDistributor] and creates provided directory if it does not exist
+func NewFileCacheDistributor(dir string, cfg ...FileCacheConfig) (*FileCacheDistributor, error) {
+	var config FileCacheConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.Key == nil {
+		config.Key = DefaultCacheKey()
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &FileCacheDistributor{
+		dir:    dir,
+		config: config,
+	}, nil
+}
+
+// Key returns cache key of the request
+func (distributor *FileCacheDistributor) Key(request *http.Request) string {
+	return distributor.config.Key(request)
+}
+
+func (distributor *FileCacheDistributor) Get(_ context.Context, request *http.Request) ([]byte, bool, error) {
+	key := distributor.Key(request)
+	path := distributor.path(key)
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	info, err := readFileCacheHeader(reader)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if info.key != key {
+		return nil, false, nil
+	}
+
+	if time.Now().After(info.expiresAt) {
+		_ = os.Remove(path)
+		return nil, false, nil
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return body, true, nil
+}
+
+func (distributor *FileCacheDistributor) Set(
+	ctx context.Context,
+	request *http.Request,
+	responseBody []byte,
+	ttl time.Duration,
+) error {
+	return distributor.SetWithMeta(ctx, request, responseBody, ttl, CacheMeta{})
+}
+
+// SetWithMeta saves response with its tags & route for invalidation
+func (distributor *FileCacheDistributor) SetWithMeta(
+	_ context.Context,
+	request *http.Request,
+	responseBody []byte,
+	ttl time.Duration,
+	meta CacheMeta,
+) error {
+	key := distributor.Key(request)
+	path := distributor.path(key)
+
+	if ttl <= 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	metaBlob, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	// write to temporary file and rename it, so readers never get partially written file
+	tmp, err := os.CreateTemp(distributor.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	header := make([]byte, fileCacheHeaderLen)
+	binary.BigEndian.PutUint64(header, uint64(time.Now().Add(ttl).UnixNano()))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(key)))
+	binary.BigEndian.PutUint32(header[12:], uint32(len(metaBlob)))
+
+	writer := bufio.NewWriter(tmp)
+	_, _ = writer.Write(header)
+	_, _ = writer.WriteString(key)
+	_, _ = writer.Write(metaBlob)
+	_, _ = writer.Write(responseBody)
+	if err = writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// DeleteExpired removes all expired responses and returns their count
+func (distributor *FileCacheDistributor) DeleteExpired(_ context.Context) (int, error) {
+	now := time.Now()
+	return distributor.invalidate(func(info fileCacheInfo) bool {
+		return now.After(info.expiresAt)
+	})
+}
+
+func (distributor *FileCacheDistributor) InvalidateTags(_ context.Context, tags ...string) (int, error) {
+	return distributor.invalidate(func(info fileCacheInfo) bool {
+		for _, tag := range tags {
+			if slices.Contains(info.meta.Tags, tag) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+func (distributor *FileCacheDistributor) InvalidatePrefix(_ context.Context, prefix string) (int, error) {
+	return distributor.invalidate(func(info fileCacheInfo) bool {
+		return strings.HasPrefix(info.key, prefix)
+	})
+}
+
+func (distributor *FileCacheDistributor) InvalidateRoute(_ context.Context, route string) (int, error) {
+	return distributor.invalidate(func(info fileCacheInfo) bool {
+		return info.meta.Route == route
+	})
+}
+
+// invalidate removes all responses matched by provided function and returns their count
+func (distributor *FileCacheDistributor) invalidate(match func(info fileCacheInfo) bool) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(distributor.dir, "*"+fileCacheExtension))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range paths {
+		info, err := readFileCacheInfo(path)
+		if err != nil || !match(info) {
+			// file could be removed concurrently
+			continue
+		}
+
+		if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (distributor *FileCacheDistributor) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(distributor.dir, hex.EncodeToString(hash[:])+fileCacheExtension)
+}
+
+// fileCacheInfo is cached response file header
+type fileCacheInfo struct {
+	key       string
+	expiresAt time.Time
+	meta      CacheMeta
+}
+
+func readFileCacheInfo(path string) (fileCacheInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return fileCacheInfo{}, err
+	}
+	defer file.Close()
+
+	return readFileCacheHeader(bufio.NewReader(file))
+}
+
+// readFileCacheHeader reads response file header, so reader is at response body start
+func readFileCacheHeader(reader io.Reader) (fileCacheInfo, error) {
+	header := make([]byte, fileCacheHeaderLen)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return fileCacheInfo{}, err
+	}
+
+	info := fileCacheInfo{
+		expiresAt: time.Unix(0, int64(binary.BigEndian.Uint64(header))),
+	}
+
+	keyLength, metaLength := binary.BigEndian.Uint32(header[8:]), binary.BigEndian.Uint32(header[12:])
+	if keyLength > fileCacheMaxHeader || metaLength > fileCacheMaxHeader {
+		return fileCacheInfo{}, errors.New("invalid cache file header")
+	}
+
+	key := make([]byte, keyLength)
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return fileCacheInfo{}, err
+	}
+	info.key = string(key)
+
+	meta := make([]byte, metaLength)
+	if _, err := io.ReadFull(reader, meta); err != nil {
+		return fileCacheInfo{}, err
+	}
+
+	if err := json.Unmarshal(meta, &info.meta); err != nil {
+		return fileCacheInfo{}, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,189 @@
+package echox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const cacheTagsKey = "cache-tags"
+
+// CacheMeta is cached response metadata used for invalidation.
+type CacheMeta struct {
+	// Tags are attached by handler with [CacheTags]
+	Tags []string `json:"tags,omitempty"`
+	// Route is method & route path template, e.g. "GET /users/:id"
+	Route string `json:"route,omitempty"`
+}
+
+// CacheMetaSetter is optional interface of [httpx.CacheDistributor] which saves response with its metadata.
+// If distributor implements it, [CacheMiddleware] uses it instead of Set.
+type CacheMetaSetter interface {
+	SetWithMeta(ctx context.Context, request *http.Request, responseBody []byte, ttl time.Duration, meta CacheMeta) error
+}
+
+// CacheInvalidator is optional interface of [httpx.CacheDistributor] which removes cached responses.
+// Every method returns count of removed responses.
+type CacheInvalidator interface {
+	// InvalidateTags removes responses with any of provided tags
+	InvalidateTags(ctx context.Context, tags ...string) (int, error)
+	// InvalidatePrefix removes responses which cache keys start with provided prefix
+	InvalidatePrefix(ctx context.Context, prefix string) (int, error)
+	// InvalidateRoute removes responses of provided route, e.g. "GET /users/:id"
+	InvalidateRoute(ctx context.Context, route string) (int, error)
+}
+
+var (
+	_cacheInvalidators   = make([]CacheInvalidator, 0)
+	_cacheInvalidatorsMx sync.RWMutex
+)
+
+// registerCacheInvalidator adds distributor used by [CacheMiddleware] to invalidation registry
+func registerCacheInvalidator(distributor any) {
+	invalidator, ok := distributor.(CacheInvalidator)
+	if !ok || !reflect.TypeOf(invalidator).Comparable() {
+		return
+	}
+
+	_cacheInvalidatorsMx.Lock()
+	defer _cacheInvalidatorsMx.Unlock()
+
+	if !slices.Contains(_cacheInvalidators, invalidator) {
+		_cacheInvalidators = append(_cacheInvalidators, invalidator)
+	}
+}
+
+// cacheTagSet collects tags attached by handler. It is shared by pointer, so it works through
+// middlewares which run handler with own context (like [TimeoutMiddleware])
+type cacheTagSet struct {
+	tags []string
+	mx   sync.Mutex
+}
+
+func (set *cacheTagSet) add(tags ...string) {
+	set.mx.Lock()
+	defer set.mx.Unlock()
+
+	for _, tag := range tags {
+		if tag != "" && !slices.Contains(set.tags, tag) {
+			set.tags = append(set.tags, tag)
+		}
+	}
+}
+
+func (set *cacheTagSet) list() []string {
+	set.mx.Lock()
+	defer set.mx.Unlock()
+
+	return slices.Clone(set.tags)
+}
+
+// CacheTags attaches tags (e.g. "user:42") to the response cached by [CacheMiddleware],
+// so it could be removed by [InvalidateTags] when entity changes
+func CacheTags(ctx echo.Context, tags ...string) {
+	if set, ok := Context(ctx).Value(cacheTagsKey).(*cacheTagSet); ok {
+		set.add(tags...)
+	}
+}
+
+// InvalidateTags removes cached responses with any of provided tags from all distributors
+// used by [CacheMiddleware] which implement [CacheInvalidator]
+func InvalidateTags(ctx context.Context, tags ...string) (int, error) {
+	return invalidateCache(func(invalidator CacheInvalidator) (int, error) {
+		return invalidator.InvalidateTags(ctx, tags...)
+	})
+}
+
+// InvalidatePrefix removes cached responses which cache keys start with provided prefix
+func InvalidatePrefix(ctx context.Context, prefix string) (int, error) {
+	return invalidateCache(func(invalidator CacheInvalidator) (int, error) {
+		return invalidator.InvalidatePrefix(ctx, prefix)
+	})
+}
+
+// InvalidateRoute removes cached responses of provided route, e.g. "GET /users/:id"
+func InvalidateRoute(ctx context.Context, route string) (int, error) {
+	return invalidateCache(func(invalidator CacheInvalidator) (int, error) {
+		return invalidator.InvalidateRoute(ctx, route)
+	})
+}
+
+func invalidateCache(invalidate func(invalidator CacheInvalidator) (int, error)) (int, error) {
+	_cacheInvalidatorsMx.RLock()
+	invalidators := slices.Clone(_cacheInvalidators)
+	_cacheInvalidatorsMx.RUnlock()
+
+	removed := 0
+	errs := make([]error, 0)
+	for _, invalidator := range invalidators {
+		count, err := invalidate(invalidator)
+		removed += count
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return removed, ErrCacheInvalidate.SetError(errors.Join(errs...))
+	}
+
+	return removed, nil
+}
+
+type cachePurgeRequest struct {
+	Tags   []string `json:"tags"`
+	Prefix string   `json:"prefix"`
+	Route  string   `json:"route"`
+}
+
+type cachePurgeResponse struct {
+	Removed int `json:"removed"`
+}
+
+// CachePurgeHandler returns admin handler which removes cached responses by JSON body:
+//
+//	{"tags": ["user:42"], "prefix": "GET /users", "route": "GET /users/:id"}
+//
+// Any combination of fields could be provided. Protect the route by auth middleware
+func CachePurgeHandler() echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		var request cachePurgeRequest
+		if err := Parse(ctx, &request); err != nil {
+			return Error(ctx, err)
+		}
+
+		if len(request.Tags) == 0 && request.Prefix == "" && request.Route == "" {
+			return Error(ctx, ErrCachePurgeEmpty)
+		}
+
+		var response cachePurgeResponse
+		purges := []struct {
+			enabled bool
+			purge   func() (int, error)
+		}{
+			{len(request.Tags) > 0, func() (int, error) { return InvalidateTags(Context(ctx), request.Tags...) }},
+			{request.Prefix != "", func() (int, error) { return InvalidatePrefix(Context(ctx), request.Prefix) }},
+			{request.Route != "", func() (int, error) { return InvalidateRoute(Context(ctx), request.Route) }},
+		}
+
+		for _, purge := range purges {
+			if !purge.enabled {
+				continue
+			}
+
+			removed, err := purge.purge()
+			response.Removed += removed
+			if err != nil {
+				return Error(ctx, err)
+			}
+		}
+
+		return Ok(ctx, response)
+	}
+}
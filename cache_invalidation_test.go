@@ -0,0 +1,76 @@
+package echox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCacheInvalidation(t *testing.T) {
+	fileDistributor, err := NewFileCacheDistributor(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := echo.New()
+	userHandler := func(ctx echo.Context) error {
+		CacheTags(ctx, "user:"+ctx.Param("id"))
+		return ctx.String(http.StatusOK, ctx.Param("id"))
+	}
+	handler.GET("/users/:id", userHandler, CacheMiddleware(time.Minute, NewMemoryCacheDistributor()))
+	handler.GET("/accounts/:id", userHandler, CacheMiddleware(time.Minute, fileDistributor))
+	handler.POST("/cache/purge", CachePurgeHandler())
+
+	paths := []string{"/users/1", "/users/2", "/accounts/1", "/accounts/2"}
+	cached := func(want map[string]bool) {
+		t.Helper()
+
+		for _, path := range paths {
+			status := serveCached(handler, path, nil).Header().Get(CacheHeader)
+			if (status == CacheHit) != want[path] {
+				t.Fatalf("%s: cache = %q, want cached = %t", path, status, want[path])
+			}
+		}
+	}
+
+	purge := func(body string) int {
+		request := httptest.NewRequest(http.MethodPost, "/cache/purge", strings.NewReader(body))
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	all := map[string]bool{"/users/1": true, "/users/2": true, "/accounts/1": true, "/accounts/2": true}
+
+	// fill cache
+	cached(nil)
+	cached(all)
+
+	// tag is removed from both distributors
+	removed, err := InvalidateTags(context.Background(), "user:1")
+	if err != nil || removed != 2 {
+		t.Fatalf("removed = %d, err = %v", removed, err)
+	}
+	cached(map[string]bool{"/users/2": true, "/accounts/2": true})
+
+	removed, err = InvalidatePrefix(context.Background(), "GET /accounts")
+	if err != nil || removed != 2 {
+		t.Fatalf("removed by prefix = %d, err = %v", removed, err)
+	}
+	cached(map[string]bool{"/users/1": true, "/users/2": true})
+
+	if status := purge(`{"route": "GET /users/:id"}`); status != http.StatusOK {
+		t.Fatalf("purge status = %d", status)
+	}
+	cached(map[string]bool{"/accounts/1": true, "/accounts/2": true})
+
+	if status := purge(`{}`); status != http.StatusBadRequest {
+		t.Fatalf("empty purge status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
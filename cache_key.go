@@ -0,0 +1,107 @@
+package echox
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// CacheKeyer is optional interface of [httpx.CacheDistributor] which returns cache key of the request.
+// [CacheMiddleware] uses it to collapse concurrent requests with the same key.
+type CacheKeyer interface {
+	Key(request *http.Request) string
+}
+
+// CacheKeyFunc returns part of the request cache key.
+type CacheKeyFunc func(request *http.Request) string
+
+// DefaultCacheKey builds cache key from method, path & sorted query
+func DefaultCacheKey() CacheKeyFunc {
+	return CacheKey(CacheKeyMethod(), CacheKeyPath(), CacheKeyQuery())
+}
+
+// CacheKey combines provided key parts
+func CacheKey(parts ...CacheKeyFunc) CacheKeyFunc {
+	return func(request *http.Request) string {
+		keys := make([]string, 0, len(parts))
+		for _, part := range parts {
+			keys = append(keys, part(request))
+		}
+
+		return strings.Join(keys, " ")
+	}
+}
+
+// CacheKeyMethod returns request method. HEAD requests have the same key as GET requests
+func CacheKeyMethod() CacheKeyFunc {
+	return func(request *http.Request) string {
+		if request.Method == http.MethodHead {
+			return http.MethodGet
+		}
+
+		return request.Method
+	}
+}
+
+// CacheKeyPath returns request path
+func CacheKeyPath() CacheKeyFunc {
+	return func(request *http.Request) string {
+		return request.URL.Path
+	}
+}
+
+// CacheKeyQuery returns query params sorted by name, so params order does not matter.
+// If names provided, only these params are used
+func CacheKeyQuery(names ...string) CacheKeyFunc {
+	return func(request *http.Request) string {
+		query := request.URL.Query()
+		if len(names) == 0 {
+			return query.Encode()
+		}
+
+		selected := make(url.Values, len(names))
+		for _, name := range names {
+			if values, ok := query[name]; ok {
+				selected[name] = values
+			}
+		}
+
+		return selected.Encode()
+	}
+}
+
+// CacheKeyHeaders returns values of provided request headers
+func CacheKeyHeaders(names ...string) CacheKeyFunc {
+	names = slices.Clone(names)
+	slices.Sort(names)
+
+	return func(request *http.Request) string {
+		values := make(url.Values, len(names))
+		for _, name := range names {
+			values[name] = request.Header.Values(name)
+		}
+
+		return values.Encode()
+	}
+}
+
+// CacheKeyIdentity returns user identity by provided function, so every user has own cached responses
+func CacheKeyIdentity(identity func(request *http.Request) string) CacheKeyFunc {
+	return func(request *http.Request) string {
+		return "identity=" + url.QueryEscape(identity(request))
+	}
+}
+
+// CacheKeyContextValue returns value from request context (e.g. user ID set by auth middleware)
+func CacheKeyContextValue(key any) CacheKeyFunc {
+	return CacheKeyIdentity(func(request *http.Request) string {
+		value := request.Context().Value(key)
+		if value == nil {
+			return ""
+		}
+
+		return fmt.Sprint(value)
+	})
+}
@@ -0,0 +1,241 @@
+package echox
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMemoryCacheEntries = 10_000
+	defaultMemoryCacheBytes   = 64 << 20 // 64MB
+)
+
+// CacheStats are cache distributor metrics.
+type CacheStats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Sets        int64 `json:"sets"`
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+	Entries     int   `json:"entries"`
+	Bytes       int64 `json:"bytes"`
+}
+
+// MemoryCacheConfig describes [MemoryCacheDistributor].
+type MemoryCacheConfig struct {
+	// MaxEntries is max count of cached responses. Default is 10000
+	MaxEntries int
+	// MaxBytes is max size of all cached responses. Default is 64MB
+	MaxBytes int64
+	// Key builds request cache key. Default is [DefaultCacheKey]
+	Key CacheKeyFunc
+}
+
+// MemoryCacheDistributor is in-memory [httpx.CacheDistributor] with LRU eviction by entries count & size.
+type MemoryCacheDistributor struct {
+	config  MemoryCacheConfig
+	entries map[string]*list.Element
+	tags    map[string]map[string]struct{}
+	lru     *list.List
+	bytes   int64
+	stats   CacheStats
+	mx      sync.Mutex
+}
+
+type memoryCacheEntry struct {
+	key       string
+	body      []byte
+	expiresAt time.Time
+	meta      CacheMeta
+}
+
+// NewMemoryCacheDistributor creates [MemoryCacheDistributor]
+func NewMemoryCacheDistributor(cfg ...MemoryCacheConfig) *MemoryCacheDistributor {
+	var config MemoryCacheConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultMemoryCacheEntries
+	}
+
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultMemoryCacheBytes
+	}
+
+	if config.Key == nil {
+		config.Key = DefaultCacheKey()
+	}
+
+	return &MemoryCacheDistributor{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		tags:    make(map[string]map[string]struct{}),
+		lru:     list.New(),
+	}
+}
+
+// Key returns cache key of the request
+func (distributor *MemoryCacheDistributor) Key(request *http.Request) string {
+	return distributor.config.Key(request)
+}
+
+func (distributor *MemoryCacheDistributor) Get(_ context.Context, request *http.Request) ([]byte, bool, error) {
+	key := distributor.Key(request)
+
+	distributor.mx.Lock()
+	defer distributor.mx.Unlock()
+
+	element, ok := distributor.entries[key]
+	if !ok {
+		distributor.stats.Misses++
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		distributor.remove(element)
+		distributor.stats.Expirations++
+		distributor.stats.Misses++
+		return nil, false, nil
+	}
+
+	distributor.lru.MoveToFront(element)
+	distributor.stats.Hits++
+	return entry.body, true, nil
+}
+
+func (distributor *MemoryCacheDistributor) Set(
+	ctx context.Context,
+	request *http.Request,
+	responseBody []byte,
+	ttl time.Duration,
+) error {
+	return distributor.SetWithMeta(ctx, request, responseBody, ttl, CacheMeta{})
+}
+
+// SetWithMeta saves response with its tags & route for invalidation
+func (distributor *MemoryCacheDistributor) SetWithMeta(
+	_ context.Context,
+	request *http.Request,
+	responseBody []byte,
+	ttl time.Duration,
+	meta CacheMeta,
+) error {
+	key := distributor.Key(request)
+	size := int64(len(responseBody))
+
+	distributor.mx.Lock()
+	defer distributor.mx.Unlock()
+
+	if element, ok := distributor.entries[key]; ok {
+		distributor.remove(element)
+	}
+
+	// response could not fit the cache at all
+	if ttl <= 0 || size > distributor.config.MaxBytes {
+		return nil
+	}
+
+	entry := &memoryCacheEntry{
+		key:       key,
+		body:      responseBody,
+		expiresAt: time.Now().Add(ttl),
+		meta:      meta,
+	}
+
+	distributor.entries[key] = distributor.lru.PushFront(entry)
+	for _, tag := range meta.Tags {
+		if distributor.tags[tag] == nil {
+			distributor.tags[tag] = make(map[string]struct{})
+		}
+
+		distributor.tags[tag][key] = struct{}{}
+	}
+	distributor.bytes += size
+	distributor.stats.Sets++
+
+	for distributor.lru.Len() > distributor.config.MaxEntries || distributor.bytes > distributor.config.MaxBytes {
+		distributor.remove(distributor.lru.Back())
+		distributor.stats.Evictions++
+	}
+
+	return nil
+}
+
+// Stats returns cache metrics
+func (distributor *MemoryCacheDistributor) Stats() CacheStats {
+	distributor.mx.Lock()
+	defer distributor.mx.Unlock()
+
+	stats := distributor.stats
+	stats.Entries = distributor.lru.Len()
+	stats.Bytes = distributor.bytes
+	return stats
+}
+
+func (distributor *MemoryCacheDistributor) InvalidateTags(_ context.Context, tags ...string) (int, error) {
+	distributor.mx.Lock()
+	defer distributor.mx.Unlock()
+
+	removed := 0
+	for _, tag := range tags {
+		for key := range distributor.tags[tag] {
+			if element, ok := distributor.entries[key]; ok {
+				distributor.remove(element)
+				removed++
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+func (distributor *MemoryCacheDistributor) InvalidatePrefix(_ context.Context, prefix string) (int, error) {
+	return distributor.invalidate(func(entry *memoryCacheEntry) bool {
+		return strings.HasPrefix(entry.key, prefix)
+	}), nil
+}
+
+func (distributor *MemoryCacheDistributor) InvalidateRoute(_ context.Context, route string) (int, error) {
+	return distributor.invalidate(func(entry *memoryCacheEntry) bool {
+		return entry.meta.Route == route
+	}), nil
+}
+
+// invalidate removes all entries matched by provided function
+func (distributor *MemoryCacheDistributor) invalidate(match func(entry *memoryCacheEntry) bool) int {
+	distributor.mx.Lock()
+	defer distributor.mx.Unlock()
+
+	removed := 0
+	for element := distributor.lru.Front(); element != nil; {
+		next := element.Next()
+		if match(element.Value.(*memoryCacheEntry)) {
+			distributor.remove(element)
+			removed++
+		}
+
+		element = next
+	}
+
+	return removed
+}
+
+func (distributor *MemoryCacheDistributor) remove(element *list.Element) {
+	entry := distributor.lru.Remove(element).(*memoryCacheEntry)
+	delete(distributor.entries, entry.key)
+	distributor.bytes -= int64(len(entry.body))
+
+	for _, tag := range entry.meta.Tags {
+		delete(distributor.tags[tag], entry.key)
+		if len(distributor.tags[tag]) == 0 {
+			delete(distributor.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package echox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cacheRequest(path string) *http.Request {
+	return httptest.NewRequest(http.MethodGet, path, nil)
+}
+
+func TestMemoryCacheDistributorEvictsByBytes(t *testing.T) {
+	ctx := context.Background()
+	distributor := NewMemoryCacheDistributor(MemoryCacheConfig{MaxBytes: 10})
+
+	_ = distributor.Set(ctx, cacheRequest("/a"), []byte("aaaa"), time.Minute)
+	_ = distributor.Set(ctx, cacheRequest("/b"), []byte("bbbb"), time.Minute)
+
+	// "/a" is used recently, so "/b" is evicted
+	if _, ok, _ := distributor.Get(ctx, cacheRequest("/a")); !ok {
+		t.Fatal("/a is not cached")
+	}
+
+	_ = distributor.Set(ctx, cacheRequest("/c"), []byte("cccc"), time.Minute)
+
+	for path, cached := range map[string]bool{"/a": true, "/b": false, "/c": true} {
+		if _, ok, _ := distributor.Get(ctx, cacheRequest(path)); ok != cached {
+			t.Errorf("%s: cached = %t, want %t", path, ok, cached)
+		}
+	}
+
+	stats := distributor.Stats()
+	if stats.Entries != 2 || stats.Bytes != 8 || stats.Evictions != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// response bigger than the whole cache is not saved and does not evict others
+	_ = distributor.Set(ctx, cacheRequest("/big"), []byte(strings.Repeat("x", 11)), time.Minute)
+	if stats = distributor.Stats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Fatalf("stats after big response = %+v", stats)
+	}
+}
+
+func TestMemoryCacheDistributorEvictsByEntries(t *testing.T) {
+	ctx := context.Background()
+	distributor := NewMemoryCacheDistributor(MemoryCacheConfig{MaxEntries: 2})
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		_ = distributor.Set(ctx, cacheRequest(path), []byte(path), time.Minute)
+	}
+
+	if _, ok, _ := distributor.Get(ctx, cacheRequest("/a")); ok {
+		t.Fatal("the oldest response is not evicted")
+	}
+
+	// replaced response does not take extra space
+	_ = distributor.Set(ctx, cacheRequest("/c"), []byte("/c2"), time.Minute)
+	if blob, ok, _ := distributor.Get(ctx, cacheRequest("/c")); !ok || string(blob) != "/c2" {
+		t.Fatalf("replaced response = %q", blob)
+	}
+
+	if stats := distributor.Stats(); stats.Entries != 2 || stats.Bytes != 5 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestMemoryCacheDistributorExpiration(t *testing.T) {
+	ctx := context.Background()
+	distributor := NewMemoryCacheDistributor()
+
+	_ = distributor.Set(ctx, cacheRequest("/a"), []byte("a"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok, _ := distributor.Get(ctx, cacheRequest("/a")); ok {
+		t.Fatal("expired response is returned")
+	}
+
+	if stats := distributor.Stats(); stats.Entries != 0 || stats.Expirations != 1 || stats.Misses != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestFileCacheDistributor(t *testing.T) {
+	ctx := context.Background()
+	distributor, err := NewFileCacheDistributor(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = distributor.Set(ctx, cacheRequest("/a?y=2&x=1"), []byte("a"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = distributor.Set(ctx, cacheRequest("/b"), []byte("b"), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// query params order does not matter
+	if blob, ok, err := distributor.Get(ctx, cacheRequest("/a?x=1&y=2")); err != nil || !ok || string(blob) != "a" {
+		t.Fatalf("blob = %q, ok = %t, err = %v", blob, ok, err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if removed, err := distributor.DeleteExpired(ctx); err != nil || removed != 1 {
+		t.Fatalf("removed = %d, err = %v", removed, err)
+	}
+
+	if _, ok, _ := distributor.Get(ctx, cacheRequest("/b")); ok {
+		t.Fatal("expired response is returned")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	request := httptest.NewRequest(http.MethodHead, "/items?b=2&a=1&c=3", nil)
+	request.Header.Set("Accept-Language", "fr")
+
+	tests := []struct {
+		name string
+		key  CacheKeyFunc
+		want string
+	}{
+		{"default", DefaultCacheKey(), "GET /items a=1&b=2&c=3"},
+		{"selected query", CacheKey(CacheKeyPath(), CacheKeyQuery("c", "a")), "/items a=1&c=3"},
+		{"headers", CacheKeyHeaders("Accept-Language"), "Accept-Language=fr"},
+		{"identity", CacheKeyIdentity(func(*http.Request) string { return "user 1" }), "identity=user+1"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.key(request); got != tt.want {
+			t.Errorf("%s: key = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package echox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// testCacheDistributor keeps responses in map by request method & URI
+type testCacheDistributor struct {
+	entries map[string][]byte
+	gets    atomic.Int32
+	mx      sync.Mutex
+}
+
+func newTestCacheDistributor() *testCacheDistributor {
+	return &testCacheDistributor{
+		entries: make(map[string][]byte),
+	}
+}
+
+func (distributor *testCacheDistributor) Get(_ context.Context, request *http.Request) ([]byte, bool, error) {
+	distributor.gets.Add(1)
+
+	distributor.mx.Lock()
+	defer distributor.mx.Unlock()
+
+	blob, ok := distributor.entries[request.Method+" "+request.URL.RequestURI()]
+	return blob, ok, nil
+}
+
+func (distributor *testCacheDistributor) Set(_ context.Context, request *http.Request, blob []byte, _ time.Duration) error {
+	distributor.mx.Lock()
+	defer distributor.mx.Unlock()
+
+	distributor.entries[request.Method+" "+request.URL.RequestURI()] = blob
+	return nil
+}
+
+func serveCached(handler http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	handler := echo.New()
+	handler.Use(CacheMiddleware(time.Minute, newTestCacheDistributor()))
+	handler.GET("/items", func(ctx echo.Context) error {
+		calls.Add(1)
+		ctx.Response().Header().Set("X-Version", "1")
+		return ctx.String(http.StatusOK, "items")
+	})
+	handler.GET("/private", func(ctx echo.Context) error {
+		calls.Add(1)
+		ctx.Response().Header().Set(echo.HeaderCacheControl, "private")
+		return ctx.String(http.StatusOK, "private")
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		cache   string
+		calls   int32
+	}{
+		{"miss", "/items", nil, CacheMiss, 1},
+		{"hit", "/items", nil, CacheHit, 1},
+		{"no-cache refreshes", "/items", map[string]string{echo.HeaderCacheControl: "no-cache"}, CacheMiss, 2},
+		{"no-store bypasses", "/items", map[string]string{echo.HeaderCacheControl: "no-store"}, CacheBypass, 3},
+		{"hit after refresh", "/items", nil, CacheHit, 3},
+		{"private response", "/private", nil, CacheMiss, 4},
+		{"private response is not saved", "/private", nil, CacheMiss, 5},
+	}
+
+	for _, tt := range tests {
+		recorder := serveCached(handler, tt.path, tt.headers)
+		if recorder.Code != http.StatusOK || recorder.Header().Get(CacheHeader) != tt.cache || calls.Load() != tt.calls {
+			t.Fatalf("%s: status = %d, cache = %q, calls = %d", tt.name, recorder.Code, recorder.Header().Get(CacheHeader), calls.Load())
+		}
+
+		if tt.cache == CacheHit && (recorder.Header().Get("X-Version") != "1" || recorder.Header().Get(ageHeader) == "") {
+			t.Fatalf("%s: cached headers are not replayed: %v", tt.name, recorder.Header())
+		}
+	}
+}
+
+func TestCacheMiddlewareVary(t *testing.T) {
+	var calls atomic.Int32
+	handler := echo.New()
+	handler.Use(CacheMiddleware(time.Minute, newTestCacheDistributor()))
+	handler.GET("/greeting", func(ctx echo.Context) error {
+		calls.Add(1)
+		ctx.Response().Header().Set(echo.HeaderVary, "Accept-Language")
+		if ctx.Request().Header.Get("Accept-Language") == "fr" {
+			return ctx.String(http.StatusOK, "bonjour")
+		}
+
+		return ctx.String(http.StatusOK, "hello")
+	})
+	handler.GET("/any", func(ctx echo.Context) error {
+		calls.Add(1)
+		ctx.Response().Header().Set(echo.HeaderVary, "*")
+		return ctx.String(http.StatusOK, "any")
+	})
+
+	tests := []struct {
+		language string
+		body     string
+		cache    string
+	}{
+		{"en", "hello", CacheMiss},
+		{"en", "hello", CacheHit},
+		// response saved for other language is not replayed
+		{"fr", "bonjour", CacheMiss},
+		{"fr", "bonjour", CacheHit},
+	}
+
+	for i, tt := range tests {
+		recorder := serveCached(handler, "/greeting", map[string]string{"Accept-Language": tt.language})
+		if recorder.Body.String() != tt.body || recorder.Header().Get(CacheHeader) != tt.cache {
+			t.Fatalf("request %d: body = %q, cache = %q", i, recorder.Body, recorder.Header().Get(CacheHeader))
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if recorder := serveCached(handler, "/any", nil); recorder.Header().Get(CacheHeader) != CacheMiss {
+			t.Fatalf("vary * request %d: cache = %q", i, recorder.Header().Get(CacheHeader))
+		}
+	}
+}
+
+func TestCacheMiddlewareCollapsesMisses(t *testing.T) {
+	const followers = 5
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	distributor := newTestCacheDistributor()
+
+	handler := echo.New()
+	handler.Use(CacheMiddleware(time.Minute, distributor))
+	handler.GET("/report", func(ctx echo.Context) error {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+
+		return ctx.String(http.StatusOK, "report")
+	})
+
+	results := make(chan *httptest.ResponseRecorder, followers+1)
+	go func() {
+		results <- serveCached(handler, "/report", nil)
+	}()
+	<-started
+
+	for i := 0; i < followers; i++ {
+		go func() {
+			results <- serveCached(handler, "/report", nil)
+		}()
+	}
+
+	// followers checked cache and wait for the leader
+	for deadline := time.Now().Add(time.Second); distributor.gets.Load() < followers+1; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("followers did not check cache")
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	hits := 0
+	for i := 0; i < followers+1; i++ {
+		recorder := <-results
+		if recorder.Body.String() != "report" {
+			t.Fatalf("body = %q", recorder.Body)
+		}
+
+		if recorder.Header().Get(CacheHeader) == CacheHit {
+			hits++
+		}
+	}
+
+	if calls.Load() != 1 || hits != followers {
+		t.Fatalf("handler calls = %d, hits = %d", calls.Load(), hits)
+	}
+}
+
+func TestCacheMiddlewareCanceledFollower(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	handler := echo.New()
+	handler.Use(CacheMiddleware(time.Minute, newTestCacheDistributor()))
+	handler.GET("/report", func(ctx echo.Context) error {
+		close(started)
+		<-release
+		return ctx.String(http.StatusOK, "report")
+	})
+
+	go serveCached(handler, "/report", nil)
+	<-started
+
+	native, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "/report", nil).WithContext(native)
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("canceled follower waits for the leader")
+	}
+}
@@ -0,0 +1,426 @@
+package echox
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ConcurrencyAIMD     = "aimd"
+	ConcurrencyGradient = "gradient"
+
+	priorityKey = "request-priority"
+
+	defaultConcurrencyInitialLimit = 20
+	defaultConcurrencyMaxLimit     = 1000
+	defaultConcurrencyQueueSize    = 100
+	defaultConcurrencyQueueTimeout = 100 * time.Millisecond
+	defaultConcurrencyLatency      = time.Second
+	defaultConcurrencyBackoff      = 0.9
+	defaultConcurrencyTolerance    = 1.5
+	defaultConcurrencyRetryAfter   = time.Second
+
+	// gradient smoothing of the long-term latency and of the limit
+	gradientLatencySmoothing = 0.05
+	gradientLimitSmoothing   = 0.2
+)
+
+// Priority of the request. Under overload requests with higher priority leave the queue first
+// and push out queued requests with lower priority.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+// ParsePriority converts priority name ("low", "normal", "high", "critical") or number to [Priority]
+func ParsePriority(value string) (Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "low":
+		return PriorityLow, true
+	case "normal":
+		return PriorityNormal, true
+	case "high":
+		return PriorityHigh, true
+	case "critical":
+		return PriorityCritical, true
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < int(PriorityLow) || number > int(PriorityCritical) {
+		return PriorityNormal, false
+	}
+
+	return Priority(number), true
+}
+
+// PriorityMiddleware sets priority of the request. Must be set before [ConcurrencyLimitMiddleware]
+func PriorityMiddleware(priority Priority) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			SetPriority(ctx, priority)
+			return next(ctx)
+		}
+	}
+}
+
+// SetPriority sets priority of the request
+func SetPriority(ctx echo.Context, priority Priority) {
+	Set(ctx, priorityKey, priority)
+}
+
+// ConcurrencyConfig describes [ConcurrencyLimitMiddleware].
+type ConcurrencyConfig struct {
+	// Algorithm changes limit by observed latency: [ConcurrencyAIMD] (default) or [ConcurrencyGradient]
+	Algorithm string
+	// InitialLimit is in-flight requests limit on start. Default is 20
+	InitialLimit int
+	// MinLimit is the lowest limit. Default is 1
+	MinLimit int
+	// MaxLimit is the highest limit. Default is 1000
+	MaxLimit int
+	// QueueSize is count of requests which could wait for free slot. Default is 100
+	QueueSize int
+	// QueueTimeout is max time of waiting in queue. Default is 100ms
+	QueueTimeout time.Duration
+	// Latency is AIMD threshold: slower requests decrease the limit. Default is 1s
+	Latency time.Duration
+	// Backoff is AIMD multiplier of the limit on slow or failed requests. Default is 0.9
+	Backoff float64
+	// Tolerance is gradient algorithm allowed ratio of current latency to long-term latency. Default is 1.5
+	Tolerance float64
+	// PriorityHeader is request header with priority (see [ParsePriority]). If empty, header is ignored
+	PriorityHeader string
+	// Routes are priorities of routes by keys like "GET /users/:id" or "/users/:id" (any method)
+	Routes map[string]Priority
+	// RetryAfter is value of "Retry-After" header of rejected requests. Default is 1s
+	RetryAfter time.Duration
+}
+
+func newConcurrencyConfig(cfg ...ConcurrencyConfig) ConcurrencyConfig {
+	var config ConcurrencyConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.Algorithm == "" {
+		config.Algorithm = ConcurrencyAIMD
+	}
+
+	if config.MinLimit <= 0 {
+		config.MinLimit = 1
+	}
+
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = defaultConcurrencyMaxLimit
+	}
+
+	if config.InitialLimit <= 0 {
+		config.InitialLimit = defaultConcurrencyInitialLimit
+	}
+	config.InitialLimit = min(max(config.InitialLimit, config.MinLimit), config.MaxLimit)
+
+	if config.QueueSize < 0 {
+		config.QueueSize = 0
+	} else if config.QueueSize == 0 {
+		config.QueueSize = defaultConcurrencyQueueSize
+	}
+
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = defaultConcurrencyQueueTimeout
+	}
+
+	if config.Latency <= 0 {
+		config.Latency = defaultConcurrencyLatency
+	}
+
+	if config.Backoff <= 0 || config.Backoff >= 1 {
+		config.Backoff = defaultConcurrencyBackoff
+	}
+
+	if config.Tolerance < 1 {
+		config.Tolerance = defaultConcurrencyTolerance
+	}
+
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = defaultConcurrencyRetryAfter
+	}
+
+	return config
+}
+
+// priority returns request priority from context, route or header
+func (config ConcurrencyConfig) priority(ctx echo.Context) Priority {
+	if priority, ok := Context(ctx).Value(priorityKey).(Priority); ok {
+		return priority
+	}
+
+	method := ctx.Request().Method
+	for _, route := range []string{method + " " + ctx.Path(), ctx.Path()} {
+		if priority, ok := config.Routes[route]; ok {
+			return priority
+		}
+	}
+
+	if config.PriorityHeader != "" {
+		if priority, ok := ParsePriority(ctx.Request().Header.Get(config.PriorityHeader)); ok {
+			return priority
+		}
+	}
+
+	return PriorityNormal
+}
+
+// ConcurrencyLimitMiddleware limits count of in-flight requests. Limit is changed by observed latency:
+//
+// - AIMD: limit grows by 1 per "limit" successful requests and is multiplied by backoff on slow or
+// overloaded (503, 504, timeout) requests;
+//
+// - gradient: limit follows ratio of long-term latency to current latency.
+//
+// If limit is reached, requests wait in priority queue for a while. Requests which could not
+// get free slot are rejected with 503 Service Unavailable and "Retry-After" header.
+//
+// Every middleware has own limiter, so use it globally (server limit) or for groups (group limits)
+func ConcurrencyLimitMiddleware(cfg ...ConcurrencyConfig) echo.MiddlewareFunc {
+	limiter := newConcurrencyLimiter(newConcurrencyConfig(cfg...))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if !limiter.acquire(Context(ctx), limiter.config.priority(ctx)) {
+				ctx.Response().Header().Set(echo.HeaderRetryAfter, durationSeconds(limiter.config.RetryAfter))
+				return Error(ctx, ErrOverloaded)
+			}
+
+			start := time.Now()
+			// slot is released even if handler panics, panic is counted as overload
+			panicked := true
+			defer func() {
+				status := ctx.Response().Status
+				overloaded := panicked ||
+					status == http.StatusServiceUnavailable ||
+					status == http.StatusGatewayTimeout ||
+					errors.Is(Context(ctx).Err(), context.DeadlineExceeded)
+
+				limiter.release(time.Since(start), overloaded)
+			}()
+
+			err := next(ctx)
+			panicked = false
+			return err
+		}
+	}
+}
+
+type concurrencyLimiter struct {
+	config   ConcurrencyConfig
+	limit    float64
+	inFlight int
+	queue    concurrencyQueue
+	sequence uint64
+
+	// gradient long-term latency (seconds)
+	longLatency float64
+
+	mx sync.Mutex
+}
+
+func newConcurrencyLimiter(config ConcurrencyConfig) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		config: config,
+		limit:  float64(config.InitialLimit),
+		queue:  make(concurrencyQueue, 0, config.QueueSize),
+	}
+}
+
+// acquire takes free slot or waits for it in queue. Returns false if slot was not taken
+func (limiter *concurrencyLimiter) acquire(ctx context.Context, priority Priority) bool {
+	limiter.mx.Lock()
+
+	if limiter.inFlight < int(limiter.limit) && limiter.queue.Len() == 0 {
+		limiter.inFlight++
+		limiter.mx.Unlock()
+		return true
+	}
+
+	if !limiter.makeRoom(priority) {
+		limiter.mx.Unlock()
+		return false
+	}
+
+	limiter.sequence++
+	waiter := &concurrencyWaiter{
+		priority: priority,
+		sequence: limiter.sequence,
+		ready:    make(chan bool, 1),
+	}
+	heap.Push(&limiter.queue, waiter)
+	limiter.mx.Unlock()
+
+	timer := time.NewTimer(limiter.config.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case granted := <-waiter.ready:
+		return granted
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	limiter.mx.Lock()
+	defer limiter.mx.Unlock()
+
+	// slot could be granted (or waiter pushed out) at the same time
+	if waiter.index < 0 {
+		return <-waiter.ready
+	}
+
+	heap.Remove(&limiter.queue, waiter.index)
+	return false
+}
+
+// makeRoom checks there is place in queue for request with provided priority.
+// Pushes out waiter with the lowest priority if queue is full
+func (limiter *concurrencyLimiter) makeRoom(priority Priority) bool {
+	if limiter.queue.Len() < limiter.config.QueueSize {
+		return true
+	}
+
+	lowest := -1
+	for i, waiter := range limiter.queue {
+		if waiter.priority < priority && (lowest < 0 || limiter.queue.Less(lowest, i)) {
+			lowest = i
+		}
+	}
+
+	if lowest < 0 {
+		return false
+	}
+
+	waiter := heap.Remove(&limiter.queue, lowest).(*concurrencyWaiter)
+	waiter.ready <- false
+	return true
+}
+
+func (limiter *concurrencyLimiter) release(latency time.Duration, overloaded bool) {
+	limiter.mx.Lock()
+	defer limiter.mx.Unlock()
+
+	limiter.inFlight--
+
+	previous := int(limiter.limit)
+	if limiter.config.Algorithm == ConcurrencyGradient {
+		limiter.gradient(latency, overloaded)
+	} else {
+		limiter.aimd(latency, overloaded)
+	}
+
+	limiter.limit = math.Min(math.Max(limiter.limit, float64(limiter.config.MinLimit)), float64(limiter.config.MaxLimit))
+	if current := int(limiter.limit); current != previous {
+		log.
+			Debug().
+			Int("previous", previous).
+			Int("limit", current).
+			Int("in_flight", limiter.inFlight).
+			Msg("Concurrency limit changed")
+	}
+
+	for limiter.inFlight < int(limiter.limit) && limiter.queue.Len() > 0 {
+		waiter := heap.Pop(&limiter.queue).(*concurrencyWaiter)
+		limiter.inFlight++
+		waiter.ready <- true
+	}
+}
+
+func (limiter *concurrencyLimiter) aimd(latency time.Duration, overloaded bool) {
+	if overloaded || latency > limiter.config.Latency {
+		limiter.limit *= limiter.config.Backoff
+		return
+	}
+
+	// grow only if limit is really used
+	if float64(limiter.inFlight+1)*2 >= limiter.limit {
+		limiter.limit += 1 / limiter.limit
+	}
+}
+
+func (limiter *concurrencyLimiter) gradient(latency time.Duration, overloaded bool) {
+	sample := latency.Seconds()
+	if limiter.longLatency == 0 {
+		limiter.longLatency = sample
+	}
+
+	limiter.longLatency = limiter.longLatency*(1-gradientLatencySmoothing) + sample*gradientLatencySmoothing
+	if sample <= 0 {
+		return
+	}
+
+	gradient := math.Min(math.Max(limiter.config.Tolerance*limiter.longLatency/sample, 0.5), 1)
+	if overloaded {
+		gradient = 0.5
+	}
+
+	// do not grow if limit is not used
+	if gradient == 1 && float64(limiter.inFlight+1)*2 < limiter.limit {
+		return
+	}
+
+	target := limiter.limit*gradient + math.Sqrt(limiter.limit)
+	limiter.limit = limiter.limit*(1-gradientLimitSmoothing) + target*gradientLimitSmoothing
+}
+
+type concurrencyWaiter struct {
+	priority Priority
+	sequence uint64
+	ready    chan bool
+	index    int
+}
+
+// concurrencyQueue is heap of waiters: higher priority first, then first come
+type concurrencyQueue []*concurrencyWaiter
+
+func (queue concurrencyQueue) Len() int {
+	return len(queue)
+}
+
+func (queue concurrencyQueue) Less(i, j int) bool {
+	if queue[i].priority != queue[j].priority {
+		return queue[i].priority > queue[j].priority
+	}
+
+	return queue[i].sequence < queue[j].sequence
+}
+
+func (queue concurrencyQueue) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+	queue[i].index = i
+	queue[j].index = j
+}
+
+func (queue *concurrencyQueue) Push(x any) {
+	waiter := x.(*concurrencyWaiter)
+	waiter.index = len(*queue)
+	*queue = append(*queue, waiter)
+}
+
+func (queue *concurrencyQueue) Pop() any {
+	old := *queue
+	waiter := old[len(old)-1]
+	old[len(old)-1] = nil
+	waiter.index = -1
+	*queue = old[:len(old)-1]
+	return waiter
+}
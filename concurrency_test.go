@@ -0,0 +1,150 @@
+package echox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	limiter := newConcurrencyLimiter(newConcurrencyConfig(ConcurrencyConfig{
+		InitialLimit: 1,
+		MaxLimit:     1,
+		QueueSize:    2,
+		QueueTimeout: time.Second,
+	}))
+
+	if !limiter.acquire(context.Background(), PriorityNormal) {
+		t.Fatal("free slot is not acquired")
+	}
+
+	order := make(chan Priority, 3)
+	wait := func(priority Priority) {
+		if limiter.acquire(context.Background(), priority) {
+			order <- priority
+			limiter.release(time.Millisecond, false)
+			return
+		}
+
+		order <- -1
+	}
+
+	go wait(PriorityLow)
+	waitQueue(t, limiter, 1)
+	go wait(PriorityHigh)
+	waitQueue(t, limiter, 2)
+
+	// queue is full: critical request pushes out low priority one
+	go wait(PriorityCritical)
+	if got := <-order; got != -1 {
+		t.Fatalf("pushed out request got slot with priority %d", got)
+	}
+
+	waitQueue(t, limiter, 2)
+	limiter.release(time.Millisecond, false)
+
+	for _, want := range []Priority{PriorityCritical, PriorityHigh} {
+		if got := <-order; got != want {
+			t.Fatalf("priority = %d, want %d", got, want)
+		}
+	}
+}
+
+func waitQueue(t *testing.T, limiter *concurrencyLimiter, size int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		limiter.mx.Lock()
+		length := limiter.queue.Len()
+		limiter.mx.Unlock()
+
+		if length == size {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("queue size is not %d", size)
+}
+
+func TestConcurrencyLimiterAIMD(t *testing.T) {
+	limiter := newConcurrencyLimiter(newConcurrencyConfig(ConcurrencyConfig{
+		InitialLimit: 10,
+		Latency:      100 * time.Millisecond,
+		Backoff:      0.5,
+	}))
+
+	limiter.acquire(context.Background(), PriorityNormal)
+	limiter.release(time.Second, false)
+	if limiter.limit != 5 {
+		t.Fatalf("limit after slow request = %v, want 5", limiter.limit)
+	}
+
+	limiter.acquire(context.Background(), PriorityNormal)
+	limiter.release(time.Millisecond, true)
+	if limiter.limit != 2.5 {
+		t.Fatalf("limit after overloaded request = %v, want 2.5", limiter.limit)
+	}
+
+	// limit grows only if it is used
+	limiter.acquire(context.Background(), PriorityNormal)
+	limiter.acquire(context.Background(), PriorityNormal)
+	limiter.release(time.Millisecond, false)
+	if limiter.limit != 2.9 {
+		t.Fatalf("limit after fast request = %v, want 2.9", limiter.limit)
+	}
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := echo.New()
+	handler.GET("/slow", func(ctx echo.Context) error {
+		close(started)
+		<-release
+		return ctx.NoContent(http.StatusOK)
+	}, ConcurrencyLimitMiddleware(ConcurrencyConfig{
+		InitialLimit: 1,
+		MaxLimit:     1,
+		QueueSize:    -1,
+		RetryAfter:   2 * time.Second,
+	}))
+	handler.GET("/panic", func(echo.Context) error {
+		panic("handler failed")
+	}, RecoverMiddleware(), ConcurrencyLimitMiddleware(ConcurrencyConfig{InitialLimit: 1, MaxLimit: 1, QueueSize: -1}))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	done := make(chan int)
+	go func() {
+		done <- send("/slow").Code
+	}()
+
+	// the first request takes the only slot
+	<-started
+	if rejected := send("/slow"); rejected.Code != http.StatusServiceUnavailable || rejected.Header().Get(echo.HeaderRetryAfter) != "2" {
+		t.Fatalf("status = %d, retry after = %q", rejected.Code, rejected.Header().Get(echo.HeaderRetryAfter))
+	}
+
+	close(release)
+	if status := <-done; status != http.StatusOK {
+		t.Fatalf("first request status = %d", status)
+	}
+
+	// slot of panicked handler is released
+	for i := 0; i < 2; i++ {
+		if recorder := send("/panic"); recorder.Code != http.StatusInternalServerError {
+			t.Fatalf("panic request %d: status = %d, want %d", i, recorder.Code, http.StatusInternalServerError)
+		}
+	}
+}
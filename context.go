@@ -48,6 +48,12 @@ func Parse(ctx echo.Context, export any) error {
 		return err
 	}
 
+	// body could be already read by other middlewares
+	if err := RewindBody(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = RewindBody(ctx) }()
+
 	if err := ctx.Bind(export); err != nil {
 		return newParseRequestBodyError(ctx, err)
 	}
@@ -63,9 +69,25 @@ func Parse(ctx echo.Context, export any) error {
 	return nil
 }
 
-// Body returns request body as []byte (slice of bytes)
+// Body returns request body as []byte (slice of bytes).
+//
+// If [BodyBufferMiddleware] is set, body could be read any number of times, otherwise only once
 func Body(ctx echo.Context) (body []byte, err error) {
-	return httpx.RequestBody(ctx.Request())
+	buffered, ok := requestBodyBuffer(ctx)
+	if !ok {
+		return httpx.RequestBody(ctx.Request())
+	}
+
+	if err = RewindBody(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { _ = RewindBody(ctx) }()
+
+	if body, err = io.ReadAll(buffered); err != nil {
+		return nil, wrapError(ErrReadRequestBody, err)
+	}
+
+	return body, nil
 }
 
 // Context returns request context as context.Context object
@@ -87,7 +109,7 @@ func Set(ctx echo.Context, key string, value any) {
 
 // File returns file as []byte (slice of bytes) from request by file name.
 //
-// Request body must be form data
+// Request body must be form data. Use [Files] to get all files of the field with their metadata
 func File(ctx echo.Context, name string) ([]byte, error) {
 	if err := contextx.Validate(Context(ctx)); err != nil {
 		return nil, err
@@ -109,7 +131,9 @@ func File(ctx echo.Context, name string) ([]byte, error) {
 
 // ParseForm get all form data object and convert them to map with [param.Param] objects.
 //
-// Notice: in this map no any files. Parse them by [File] function
+// Notice: in this map no any files. Parse them by [File] function.
+//
+// Only the first value of every key is returned, use [FormValues] to get all of them
 func ParseForm(ctx echo.Context) (map[string]httpx.Param, error) {
 	if err := contextx.Validate(Context(ctx)); err != nil {
 		return nil, err
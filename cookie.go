@@ -0,0 +1,343 @@
+package echox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultCookieTTL = 7 * 24 * time.Hour
+	minCookieKeySize = 16
+)
+
+// CookieOptions describes cookie attributes. By default, cookies are Secure, HttpOnly, SameSite=Lax,
+// have path "/" and live 7 days.
+type CookieOptions struct {
+	// TTL is cookie lifetime. Signed & encrypted cookies are rejected after TTL even if browser still sends them
+	TTL time.Duration
+	// Path of the cookie. Default is "/"
+	Path string
+	// Domain of the cookie
+	Domain string
+	// SameSite of the cookie. Default is Lax
+	SameSite http.SameSite
+	// Insecure allows cookie over plain HTTP (disables Secure attribute)
+	Insecure bool
+	// ScriptAccess allows cookie access from JavaScript (disables HttpOnly attribute)
+	ScriptAccess bool
+	// Partitioned stores cookie per top-level site (CHIPS), requires Secure
+	Partitioned bool
+}
+
+func newCookieOptions(opts ...CookieOptions) CookieOptions {
+	var options CookieOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.TTL <= 0 {
+		options.TTL = defaultCookieTTL
+	}
+
+	if options.Path == "" {
+		options.Path = "/"
+	}
+
+	if options.SameSite == 0 {
+		options.SameSite = http.SameSiteLaxMode
+	}
+
+	return options
+}
+
+func (options CookieOptions) cookie(name, value string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:        name,
+		Value:       value,
+		Path:        options.Path,
+		Domain:      options.Domain,
+		Expires:     expiresAt,
+		Secure:      !options.Insecure,
+		HttpOnly:    !options.ScriptAccess,
+		SameSite:    options.SameSite,
+		Partitioned: options.Partitioned,
+	}
+}
+
+var (
+	_cookieKeys   *cookieKeyring
+	_cookieKeysMx sync.RWMutex
+)
+
+// RegisterCookieKeys sets keys of signed & encrypted cookies. Every key must be at least 16 bytes.
+// Cookies are signed & encrypted by the first key and verified by any of keys, so keys could be rotated:
+// new key is added first and old key is removed after cookies TTL
+func RegisterCookieKeys(keys ...[]byte) error {
+	keyring, err := newCookieKeyring(keys...)
+	if err != nil {
+		return err
+	}
+
+	_cookieKeysMx.Lock()
+	defer _cookieKeysMx.Unlock()
+
+	_cookieKeys = keyring
+	return nil
+}
+
+func cookieKeys() (*cookieKeyring, error) {
+	_cookieKeysMx.RLock()
+	defer _cookieKeysMx.RUnlock()
+
+	if _cookieKeys == nil {
+		return nil, ErrCookieKeys.SetError(errors.New("cookie keys are not registered"))
+	}
+
+	return _cookieKeys, nil
+}
+
+// SetSignedCookie sets cookie which value is readable by client but could not be changed
+func SetSignedCookie(ctx echo.Context, name, value string, opts ...CookieOptions) error {
+	keyring, err := cookieKeys()
+	if err != nil {
+		return err
+	}
+
+	options := newCookieOptions(opts...)
+	expiresAt := time.Now().Add(options.TTL)
+	ctx.SetCookie(options.cookie(name, keyring.sign(name, value, expiresAt), expiresAt))
+	return nil
+}
+
+// SignedCookie returns value of cookie set by [SetSignedCookie].
+// Returns ErrCookieMissing if there is no cookie (or it is expired) and ErrCookieTampered if signature is invalid
+func SignedCookie(ctx echo.Context, name string) (string, error) {
+	keyring, err := cookieKeys()
+	if err != nil {
+		return "", err
+	}
+
+	cookie, err := ctx.Cookie(name)
+	if err != nil {
+		return "", ErrCookieMissing
+	}
+
+	return keyring.verify(name, cookie.Value)
+}
+
+// SetEncryptedCookie sets cookie which value could not be read or changed by client
+func SetEncryptedCookie(ctx echo.Context, name, value string, opts ...CookieOptions) error {
+	keyring, err := cookieKeys()
+	if err != nil {
+		return err
+	}
+
+	options := newCookieOptions(opts...)
+	expiresAt := time.Now().Add(options.TTL)
+	sealed, err := keyring.encrypt(name, []byte(value), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	ctx.SetCookie(options.cookie(name, sealed, expiresAt))
+	return nil
+}
+
+// EncryptedCookie returns value of cookie set by [SetEncryptedCookie].
+// Returns ErrCookieMissing if there is no cookie (or it is expired) and ErrCookieTampered if it could not be decrypted
+func EncryptedCookie(ctx echo.Context, name string) (string, error) {
+	keyring, err := cookieKeys()
+	if err != nil {
+		return "", err
+	}
+
+	cookie, err := ctx.Cookie(name)
+	if err != nil {
+		return "", ErrCookieMissing
+	}
+
+	value, err := keyring.decrypt(name, cookie.Value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
+
+// DeleteCookie expires cookie
+func DeleteCookie(ctx echo.Context, name string, opts ...CookieOptions) {
+	cookie := newCookieOptions(opts...).cookie(name, "", time.Unix(0, 0))
+	cookie.MaxAge = -1
+	ctx.SetCookie(cookie)
+}
+
+// cookieKeyring signs & encrypts cookie values. Sign & encryption keys are derived from provided keys,
+// so the same key is never used for different algorithms
+type cookieKeyring struct {
+	signKeys [][]byte
+	ciphers  []cipher.AEAD
+}
+
+func newCookieKeyring(keys ...[]byte) (*cookieKeyring, error) {
+	if len(keys) == 0 {
+		return nil, ErrCookieKeys.SetError(errors.New("at least one key must be provided"))
+	}
+
+	keyring := &cookieKeyring{
+		signKeys: make([][]byte, 0, len(keys)),
+		ciphers:  make([]cipher.AEAD, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		if len(key) < minCookieKeySize {
+			return nil, ErrCookieKeys.SetError(errors.New("key must be at least 16 bytes"))
+		}
+
+		block, err := aes.NewCipher(deriveCookieKey(key, "encrypt"))
+		if err != nil {
+			return nil, ErrCookieKeys.SetError(err)
+		}
+
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, ErrCookieKeys.SetError(err)
+		}
+
+		keyring.signKeys = append(keyring.signKeys, deriveCookieKey(key, "sign"))
+		keyring.ciphers = append(keyring.ciphers, gcm)
+	}
+
+	return keyring, nil
+}
+
+func deriveCookieKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("echox-cookie-" + purpose))
+	return mac.Sum(nil)
+}
+
+// sign returns "value.expiration.signature", where signature covers cookie name, so signed value
+// of one cookie could not be used as another cookie
+func (keyring *cookieKeyring) sign(name, value string, expiresAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(cookieMAC(keyring.signKeys[0], name, payload))
+}
+
+func (keyring *cookieKeyring) verify(name, signed string) (string, error) {
+	payload, signature, ok := cutLast(signed, ".")
+	if !ok {
+		return "", ErrCookieTampered
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+
+	valid := false
+	for _, key := range keyring.signKeys {
+		if hmac.Equal(mac, cookieMAC(key, name, payload)) {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		return "", ErrCookieTampered
+	}
+
+	encoded, expiration, ok := cutLast(payload, ".")
+	if !ok {
+		return "", ErrCookieTampered
+	}
+
+	expiresAt, err := strconv.ParseInt(expiration, 10, 64)
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+
+	if time.Now().After(time.Unix(expiresAt, 0)) {
+		return "", ErrCookieMissing
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrCookieTampered
+	}
+
+	return string(value), nil
+}
+
+// encrypt returns AES-GCM encrypted expiration & value. Cookie name is authenticated as additional data.
+// Zero expiration time means value never expires
+func (keyring *cookieKeyring) encrypt(name string, value []byte, expiresAt time.Time) (string, error) {
+	plaintext := make([]byte, 8, 8+len(value))
+	if !expiresAt.IsZero() {
+		binary.BigEndian.PutUint64(plaintext, uint64(expiresAt.Unix()))
+	}
+	plaintext = append(plaintext, value...)
+
+	gcm := keyring.ciphers[0]
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", ErrCookieKeys.SetError(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, []byte(name))), nil
+}
+
+func (keyring *cookieKeyring) decrypt(name, sealed string) ([]byte, error) {
+	blob, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, ErrCookieTampered
+	}
+
+	for _, gcm := range keyring.ciphers {
+		if len(blob) < gcm.NonceSize()+8 {
+			continue
+		}
+
+		nonce, ciphertext := blob[:gcm.NonceSize()], blob[gcm.NonceSize():]
+		plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(name))
+		if err != nil {
+			continue
+		}
+
+		expiresAt := int64(binary.BigEndian.Uint64(plaintext))
+		if expiresAt != 0 && time.Now().After(time.Unix(expiresAt, 0)) {
+			return nil, ErrCookieMissing
+		}
+
+		return plaintext[8:], nil
+	}
+
+	return nil, ErrCookieTampered
+}
+
+func cookieMAC(key []byte, name, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + payload))
+	return mac.Sum(nil)
+}
+
+func cutLast(value, separator string) (before, after string, found bool) {
+	index := strings.LastIndex(value, separator)
+	if index < 0 {
+		return value, "", false
+	}
+
+	return value[:index], value[index+len(separator):], true
+}
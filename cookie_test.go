@@ -0,0 +1,201 @@
+package echox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boostgo/errorx"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	testCookieKey    = []byte("0123456789abcdef")
+	testCookieNewKey = []byte("fedcba9876543210")
+)
+
+func registerCookieKeys(t *testing.T, keys ...[]byte) {
+	t.Helper()
+
+	_cookieKeysMx.RLock()
+	previous := _cookieKeys
+	_cookieKeysMx.RUnlock()
+
+	if err := RegisterCookieKeys(keys...); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_cookieKeysMx.Lock()
+		_cookieKeys = previous
+		_cookieKeysMx.Unlock()
+	})
+}
+
+// setCookie runs set function and returns cookie written to the response
+func setCookie(t *testing.T, set func(ctx echo.Context) error) *http.Cookie {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), recorder)
+	if err := set(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v", cookies)
+	}
+
+	return cookies[0]
+}
+
+// readCookie reads cookie with provided name & value by read function
+func readCookie(name, value string, read func(ctx echo.Context, name string) (string, error)) (string, error) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if value != "" {
+		request.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	return read(echo.New().NewContext(request, httptest.NewRecorder()), name)
+}
+
+func TestSignedCookie(t *testing.T) {
+	registerCookieKeys(t, testCookieKey)
+
+	cookie := setCookie(t, func(ctx echo.Context) error {
+		return SetSignedCookie(ctx, "theme", "dark")
+	})
+
+	if !cookie.Secure || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/" {
+		t.Fatalf("cookie attributes = %+v", cookie)
+	}
+
+	keyring, _ := newCookieKeyring(testCookieKey)
+	payload, signature, _ := cutLast(cookie.Value, ".")
+	_, expiration, _ := cutLast(payload, ".")
+	expired := keyring.sign("theme", "dark", time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   *errorx.Error
+	}{
+		{name: "valid", value: cookie.Value, want: "dark"},
+		{name: "missing", err: ErrCookieMissing},
+		{name: "expired", value: expired, err: ErrCookieMissing},
+		{name: "changed value", value: "bGlnaHQ." + expiration + "." + signature, err: ErrCookieTampered},
+		{name: "changed expiration", value: "ZGFyaw.9999999999." + signature, err: ErrCookieTampered},
+		{name: "changed signature", value: payload + ".AAAA", err: ErrCookieTampered},
+		{name: "no signature", value: "dark", err: ErrCookieTampered},
+		// value signed for other cookie could not be used
+		{name: "other cookie", value: keyring.sign("role", "dark", time.Now().Add(time.Hour)), err: ErrCookieTampered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := readCookie("theme", tt.value, SignedCookie)
+			if tt.err != nil {
+				if !isError(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil || value != tt.want {
+				t.Fatalf("value = %q, err = %v", value, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	registerCookieKeys(t, testCookieKey)
+
+	cookie := setCookie(t, func(ctx echo.Context) error {
+		return SetEncryptedCookie(ctx, "cart", "42")
+	})
+
+	keyring, _ := newCookieKeyring(testCookieKey)
+	expired, _ := keyring.encrypt("cart", []byte("42"), time.Now().Add(-time.Minute))
+	other, _ := keyring.encrypt("wishlist", []byte("42"), time.Now().Add(time.Hour))
+	tampered := []byte(cookie.Value)
+	tampered[len(tampered)/2] ^= 1
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   *errorx.Error
+	}{
+		{name: "valid", value: cookie.Value, want: "42"},
+		{name: "missing", err: ErrCookieMissing},
+		{name: "expired", value: expired, err: ErrCookieMissing},
+		{name: "tampered", value: string(tampered), err: ErrCookieTampered},
+		{name: "other cookie", value: other, err: ErrCookieTampered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := readCookie("cart", tt.value, EncryptedCookie)
+			if tt.err != nil {
+				if !isError(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil || value != tt.want {
+				t.Fatalf("value = %q, err = %v", value, err)
+			}
+		})
+	}
+}
+
+func TestCookieKeyRotation(t *testing.T) {
+	registerCookieKeys(t, testCookieKey)
+	signed := setCookie(t, func(ctx echo.Context) error {
+		return SetSignedCookie(ctx, "theme", "dark")
+	})
+	encrypted := setCookie(t, func(ctx echo.Context) error {
+		return SetEncryptedCookie(ctx, "cart", "42")
+	})
+
+	// new key is added first, old one still verifies cookies
+	registerCookieKeys(t, testCookieNewKey, testCookieKey)
+	if value, err := readCookie("theme", signed.Value, SignedCookie); err != nil || value != "dark" {
+		t.Fatalf("signed by old key: value = %q, err = %v", value, err)
+	}
+
+	if value, err := readCookie("cart", encrypted.Value, EncryptedCookie); err != nil || value != "42" {
+		t.Fatalf("encrypted by old key: value = %q, err = %v", value, err)
+	}
+
+	// new cookies are signed by new key only
+	rotated := setCookie(t, func(ctx echo.Context) error {
+		return SetSignedCookie(ctx, "theme", "light")
+	})
+
+	newKeyring, _ := newCookieKeyring(testCookieNewKey)
+	if value, err := newKeyring.verify("theme", rotated.Value); err != nil || value != "light" {
+		t.Fatalf("signed by new key: value = %q, err = %v", value, err)
+	}
+
+	// old key is removed
+	registerCookieKeys(t, testCookieNewKey)
+	if _, err := readCookie("theme", signed.Value, SignedCookie); !isError(err, ErrCookieTampered) {
+		t.Fatalf("error = %v, want %v", err, ErrCookieTampered)
+	}
+}
+
+func TestRegisterCookieKeysInvalid(t *testing.T) {
+	for _, keys := range [][][]byte{nil, {[]byte("short")}} {
+		if err := RegisterCookieKeys(keys...); !isError(err, ErrCookieKeys) {
+			t.Errorf("keys %q: error = %v, want %v", keys, err, ErrCookieKeys)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package echox
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	csrfKey = "csrf-token"
+
+	defaultCSRFCookie = "_csrf"
+	defaultCSRFHeader = "X-CSRF-Token"
+	defaultCSRFForm   = "_csrf"
+	csrfSessionKey    = "csrf_token"
+	csrfTokenLength   = 32
+)
+
+// CSRFMode is the way CSRF token is kept between requests.
+type CSRFMode string
+
+const (
+	// CSRFDoubleSubmit keeps token in cookie. Request must send the same token in header or form field
+	CSRFDoubleSubmit CSRFMode = "double_submit"
+	// CSRFSynchronizer keeps token in session created by [SessionMiddleware]
+	CSRFSynchronizer CSRFMode = "synchronizer"
+)
+
+// CSRFConfig describes [CSRFMiddleware].
+type CSRFConfig struct {
+	// Mode is the way token is kept. Default is double submit cookie
+	Mode CSRFMode
+	// Cookie is token cookie name in double submit mode. Default is "_csrf".
+	// Cookie is signed by keys registered by [RegisterCookieKeys]
+	Cookie string
+	// CookieOptions are token cookie attributes in double submit mode.
+	// Set ScriptAccess if token is read from cookie by JavaScript: whole signed cookie value is sent back
+	CookieOptions CookieOptions
+	// Header with token. Default is "X-CSRF-Token"
+	Header string
+	// Form is form field with token. Default is "_csrf"
+	Form string
+	// ExposeHeader sets token to response header of safe requests, so SPA could read it
+	ExposeHeader bool
+	// TrustedOrigins are origins allowed besides request host, e.g. "https://app.example.com"
+	TrustedOrigins []string
+	// Exempt are routes without CSRF check by keys like "POST /webhooks" or "/webhooks" (any method)
+	Exempt []string
+}
+
+func newCSRFConfig(cfg ...CSRFConfig) CSRFConfig {
+	var config CSRFConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.Mode == "" {
+		config.Mode = CSRFDoubleSubmit
+	}
+
+	if config.Cookie == "" {
+		config.Cookie = defaultCSRFCookie
+	}
+
+	if config.Header == "" {
+		config.Header = defaultCSRFHeader
+	}
+
+	if config.Form == "" {
+		config.Form = defaultCSRFForm
+	}
+
+	origins := make([]string, 0, len(config.TrustedOrigins))
+	for _, origin := range config.TrustedOrigins {
+		origins = append(origins, strings.ToLower(strings.TrimSuffix(origin, "/")))
+	}
+	config.TrustedOrigins = origins
+
+	return config
+}
+
+func (config CSRFConfig) exempt(ctx echo.Context) bool {
+	method := ctx.Request().Method
+	return slices.Contains(config.Exempt, method+" "+ctx.Path()) || slices.Contains(config.Exempt, ctx.Path())
+}
+
+// CSRFMiddleware protects cookie authenticated routes from cross-site request forgery.
+//
+// Every request gets CSRF token available by [CSRFToken] function for templates (and by response header
+// if enabled). Requests with unsafe methods (POST, PUT, PATCH, DELETE, etc.) must send the token
+// in header or form field and come from the same origin (by "Origin" or "Referer" header for HTTPS).
+//
+// Failed checks get 403 Forbidden. In double submit mode token cookie is signed, so cookie keys must be
+// registered by [RegisterCookieKeys]. In synchronizer mode [SessionMiddleware] must be used before
+func CSRFMiddleware(cfg ...CSRFConfig) echo.MiddlewareFunc {
+	config := newCSRFConfig(cfg...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			token, err := csrfLoadToken(ctx, config)
+			if err != nil {
+				return Error(ctx, err)
+			}
+
+			Set(ctx, csrfKey, token)
+
+			if csrfSafeMethod(ctx.Request().Method) {
+				if config.ExposeHeader {
+					ctx.Response().Header().Set(config.Header, maskCSRFToken(token))
+				}
+
+				return next(ctx)
+			}
+
+			if config.exempt(ctx) {
+				return next(ctx)
+			}
+
+			if err = csrfCheckOrigin(ctx, config); err != nil {
+				return Error(ctx, err)
+			}
+
+			submitted := ctx.Request().Header.Get(config.Header)
+			if submitted == "" {
+				submitted = ctx.FormValue(config.Form)
+			}
+
+			if submitted == "" {
+				return Error(ctx, ErrCSRFTokenMissing)
+			}
+
+			if !csrfTokenEqual(token, csrfSubmittedToken(config, submitted)) {
+				return Error(ctx, ErrCSRFTokenInvalid)
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// CSRFToken returns CSRF token of the request for templates or response headers.
+// Token is masked by random value on every call, so it is not exposed to compression attacks (BREACH)
+func CSRFToken(ctx echo.Context) string {
+	token, ok := Context(ctx).Value(csrfKey).([]byte)
+	if !ok {
+		return ""
+	}
+
+	return maskCSRFToken(token)
+}
+
+// csrfLoadToken returns token kept in cookie or session. New token is created if there is no token yet
+func csrfLoadToken(ctx echo.Context, config CSRFConfig) ([]byte, error) {
+	if config.Mode == CSRFSynchronizer {
+		session := Session(ctx)
+		if encoded, ok := SessionValue[string](ctx, csrfSessionKey); ok {
+			if token, ok := decodeCSRFToken(encoded); ok {
+				return token, nil
+			}
+		}
+
+		token, err := newCSRFToken()
+		if err != nil {
+			return nil, err
+		}
+
+		if err = session.Set(csrfSessionKey, base64.RawURLEncoding.EncodeToString(token)); err != nil {
+			return nil, err
+		}
+
+		return token, nil
+	}
+
+	// signed cookie could not be set by attacker (e.g. from sibling subdomain), missing,
+	// expired & tampered cookies are replaced by new token
+	encoded, err := SignedCookie(ctx, config.Cookie)
+	if err != nil && isError(err, ErrCookieKeys) {
+		return nil, err
+	}
+
+	if err == nil {
+		if token, ok := decodeCSRFToken(encoded); ok {
+			return token, nil
+		}
+	}
+
+	token, err := newCSRFToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = SetSignedCookie(ctx, config.Cookie, base64.RawURLEncoding.EncodeToString(token), config.CookieOptions); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// csrfSubmittedToken returns token sent by client. It is masked token (by [CSRFToken] or response header),
+// not masked token or signed token cookie value read by JavaScript in double submit mode
+func csrfSubmittedToken(config CSRFConfig, submitted string) []byte {
+	if config.Mode != CSRFDoubleSubmit || !strings.Contains(submitted, ".") {
+		return unmaskCSRFToken(submitted)
+	}
+
+	keyring, err := cookieKeys()
+	if err != nil {
+		return nil
+	}
+
+	encoded, err := keyring.verify(config.Cookie, submitted)
+	if err != nil {
+		return nil
+	}
+
+	token, ok := decodeCSRFToken(encoded)
+	if !ok {
+		return nil
+	}
+
+	return token
+}
+
+// csrfCheckOrigin checks that request came from the same or trusted origin. If there is no "Origin" header,
+// "Referer" is checked for HTTPS requests (plain HTTP requests often have no referer because of privacy settings)
+func csrfCheckOrigin(ctx echo.Context, config CSRFConfig) error {
+	self := strings.ToLower(BaseURL(ctx))
+
+	origin := ctx.Request().Header.Get(echo.HeaderOrigin)
+	if origin == "" {
+		if RequestScheme(ctx) != schemeHTTPS {
+			return nil
+		}
+
+		referer, err := url.Parse(ctx.Request().Referer())
+		if err != nil || referer.Host == "" {
+			return newCSRFOriginError("")
+		}
+
+		origin = referer.Scheme + "://" + referer.Host
+	}
+
+	origin = strings.ToLower(origin)
+	if origin == self || slices.Contains(config.TrustedOrigins, origin) {
+		return nil
+	}
+
+	return newCSRFOriginError(origin)
+}
+
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func newCSRFToken() ([]byte, error) {
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, ErrCSRF.SetError(err)
+	}
+
+	return token, nil
+}
+
+func decodeCSRFToken(encoded string) ([]byte, bool) {
+	token, err := base64.RawURLEncoding.DecodeString(encoded)
+	return token, err == nil && len(token) == csrfTokenLength
+}
+
+// maskCSRFToken returns base64 of random one-time pad & token XOR pad
+func maskCSRFToken(token []byte) string {
+	masked := make([]byte, 2*len(token))
+	pad := masked[:len(token)]
+	_, _ = rand.Read(pad)
+	for index := range token {
+		masked[len(token)+index] = pad[index] ^ token[index]
+	}
+
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// unmaskCSRFToken returns token from masked value. Not masked token is returned as is
+func unmaskCSRFToken(masked string) []byte {
+	blob, err := base64.RawURLEncoding.DecodeString(masked)
+	if err != nil {
+		return nil
+	}
+
+	if len(blob) == csrfTokenLength {
+		return blob
+	}
+
+	if len(blob) != 2*csrfTokenLength {
+		return nil
+	}
+
+	token := make([]byte, csrfTokenLength)
+	for index := range token {
+		token[index] = blob[index] ^ blob[csrfTokenLength+index]
+	}
+
+	return token
+}
+
+func csrfTokenEqual(expected, actual []byte) bool {
+	return len(actual) == len(expected) && subtle.ConstantTimeCompare(expected, actual) == 1
+}
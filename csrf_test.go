@@ -0,0 +1,268 @@
+package echox
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newCSRFHandler(middlewares ...echo.MiddlewareFunc) *echo.Echo {
+	handler := echo.New()
+	handler.Use(middlewares...)
+	handler.GET("/form", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, CSRFToken(ctx))
+	})
+	handler.POST("/orders", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusCreated)
+	})
+	handler.POST("/webhooks", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusCreated)
+	})
+
+	return handler
+}
+
+type csrfRequest struct {
+	path    string
+	cookies []*http.Cookie
+	headers map[string]string
+	form    url.Values
+	https   bool
+}
+
+func serveCSRF(handler http.Handler, method string, csrf csrfRequest) *httptest.ResponseRecorder {
+	var request *http.Request
+	if csrf.form != nil {
+		request = httptest.NewRequest(method, csrf.path, strings.NewReader(csrf.form.Encode()))
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	} else {
+		request = httptest.NewRequest(method, csrf.path, nil)
+	}
+
+	for _, cookie := range csrf.cookies {
+		request.AddCookie(cookie)
+	}
+
+	for name, value := range csrf.headers {
+		request.Header.Set(name, value)
+	}
+
+	if csrf.https {
+		request.TLS = &tls.ConnectionState{}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	registerCookieKeys(t, testCookieKey)
+
+	handler := newCSRFHandler(CSRFMiddleware(CSRFConfig{
+		ExposeHeader:   true,
+		TrustedOrigins: []string{"https://app.example.com/"},
+		Exempt:         []string{"POST /webhooks"},
+	}))
+
+	// safe request gets token & signed cookie
+	form := serveCSRF(handler, http.MethodGet, csrfRequest{path: "/form"})
+	cookie := responseCookie(form, defaultCSRFCookie)
+	masked := form.Body.String()
+	if form.Code != http.StatusOK || cookie == nil || masked == "" || form.Header().Get(defaultCSRFHeader) == "" {
+		t.Fatalf("status = %d, cookie = %v, token = %q", form.Code, cookie, masked)
+	}
+
+	keyring, _ := newCookieKeyring(testCookieKey)
+	unmasked, err := keyring.verify(defaultCSRFCookie, cookie.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// masking is random, but token is the same
+	if again := serveCSRF(handler, http.MethodGet, csrfRequest{path: "/form", cookies: []*http.Cookie{cookie}}); again.Body.String() == masked ||
+		responseCookie(again, defaultCSRFCookie) != nil {
+		t.Fatalf("token is not masked or cookie is replaced: %q", again.Body.String())
+	}
+
+	cookies := []*http.Cookie{cookie}
+	tests := []struct {
+		name    string
+		request csrfRequest
+		status  int
+		error   string
+	}{
+		{
+			name:    "masked token in header",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: masked}},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "masked token in form",
+			request: csrfRequest{cookies: cookies, form: url.Values{defaultCSRFForm: {masked}}},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "not masked token",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: unmasked}},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "signed cookie value",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: cookie.Value}},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "missing token",
+			request: csrfRequest{cookies: cookies},
+			status:  http.StatusForbidden,
+			error:   "csrf_token_missing",
+		},
+		{
+			name:    "other token",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: base64.RawURLEncoding.EncodeToString(make([]byte, csrfTokenLength))}},
+			status:  http.StatusForbidden,
+			error:   "csrf_token_invalid",
+		},
+		{
+			name:    "missing cookie",
+			request: csrfRequest{headers: map[string]string{defaultCSRFHeader: masked}},
+			status:  http.StatusForbidden,
+			error:   "csrf_token_invalid",
+		},
+		{
+			// attacker could set cookie, but could not sign it
+			name: "not signed cookie",
+			request: csrfRequest{
+				cookies: []*http.Cookie{{Name: defaultCSRFCookie, Value: unmasked}},
+				headers: map[string]string{defaultCSRFHeader: unmasked},
+			},
+			status: http.StatusForbidden,
+			error:  "csrf_token_invalid",
+		},
+		{
+			name:    "exempt route",
+			request: csrfRequest{path: "/webhooks"},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "other origin",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: masked, echo.HeaderOrigin: "https://evil.example"}},
+			status:  http.StatusForbidden,
+			error:   "csrf_origin_mismatch",
+		},
+		{
+			name:    "same origin",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: masked, echo.HeaderOrigin: "http://example.com"}},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "trusted origin",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: masked, echo.HeaderOrigin: "https://app.example.com"}},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "HTTPS without referer",
+			request: csrfRequest{cookies: cookies, headers: map[string]string{defaultCSRFHeader: masked}, https: true},
+			status:  http.StatusForbidden,
+			error:   "csrf_origin_mismatch",
+		},
+		{
+			name: "HTTPS same referer",
+			request: csrfRequest{
+				cookies: cookies,
+				headers: map[string]string{defaultCSRFHeader: masked, "Referer": "https://example.com/form"},
+				https:   true,
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name: "HTTPS other referer",
+			request: csrfRequest{
+				cookies: cookies,
+				headers: map[string]string{defaultCSRFHeader: masked, "Referer": "https://evil.example/form"},
+				https:   true,
+			},
+			status: http.StatusForbidden,
+			error:  "csrf_origin_mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.request.path == "" {
+				tt.request.path = "/orders"
+			}
+
+			recorder := serveCSRF(handler, http.MethodPost, tt.request)
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+
+			if !strings.Contains(recorder.Body.String(), tt.error) {
+				t.Fatalf("body = %s, want %s", recorder.Body.String(), tt.error)
+			}
+		})
+	}
+}
+
+func TestCSRFMiddlewareWithoutCookieKeys(t *testing.T) {
+	// registered keys are restored after the test
+	registerCookieKeys(t, testCookieKey)
+	_cookieKeysMx.Lock()
+	_cookieKeys = nil
+	_cookieKeysMx.Unlock()
+
+	handler := newCSRFHandler(CSRFMiddleware())
+	if recorder := serveCSRF(handler, http.MethodGet, csrfRequest{path: "/form"}); recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestCSRFMiddlewareSynchronizer(t *testing.T) {
+	handler := newCSRFHandler(SessionMiddleware(), CSRFMiddleware(CSRFConfig{Mode: CSRFSynchronizer}))
+
+	form := serveCSRF(handler, http.MethodGet, csrfRequest{path: "/form"})
+	session := responseCookie(form, defaultSessionCookie)
+	token := form.Body.String()
+	if session == nil || token == "" || responseCookie(form, defaultCSRFCookie) != nil {
+		t.Fatalf("session = %v, token = %q, cookies = %v", session, token, form.Result().Cookies())
+	}
+
+	tests := []struct {
+		name    string
+		request csrfRequest
+		status  int
+	}{
+		{
+			name:    "session token",
+			request: csrfRequest{cookies: []*http.Cookie{session}, headers: map[string]string{defaultCSRFHeader: token}},
+			status:  http.StatusCreated,
+		},
+		{
+			name:    "without session",
+			request: csrfRequest{headers: map[string]string{defaultCSRFHeader: token}},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "without token",
+			request: csrfRequest{cookies: []*http.Cookie{session}},
+			status:  http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.path = "/orders"
+			if recorder := serveCSRF(handler, http.MethodPost, tt.request); recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package echox
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+// DownloadConfig describes [Download] response.
+type DownloadConfig struct {
+	// ContentType of the response. By default, is defined by file name extension or sniffed from content
+	ContentType string
+	// Inline sets "inline" Content-Disposition (show in browser) instead of "attachment" (download)
+	Inline bool
+	// ModTime is content modification time for Last-Modified header. By default, is taken from [fs.File] stat
+	ModTime time.Time
+	// ETag is entity tag of the content. By default, is generated from content hash (for bytes)
+	// or from size & modification time (for files)
+	ETag string
+}
+
+// Download returns file response from provided content which must be []byte, [io.ReadSeeker] or [fs.File].
+//
+// Response supports Range & If-Range requests (including multipart/byteranges), conditional requests
+// by Last-Modified & ETag and HEAD method.
+//
+// Content-Disposition header contains provided name in RFC 6266 format (with UTF-8 filename).
+//
+// Sets trace id to the response if it was in request context
+func Download(ctx echo.Context, name string, content any, cfg ...DownloadConfig) error {
+	var config DownloadConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	reader, closer, err := downloadReader(content, &config)
+	if closer != nil {
+		defer closer.Close()
+	}
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	setTraceHeader(ctx)
+
+	header := ctx.Response().Header()
+	if config.ContentType == "" {
+		config.ContentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if config.ContentType != "" {
+		header.Set(echo.HeaderContentType, config.ContentType)
+	}
+	if config.ETag != "" {
+		header.Set("ETag", config.ETag)
+	}
+	header.Set(echo.HeaderContentDisposition, ContentDisposition(name, config.Inline))
+
+	// http.ServeContent handles ranges, conditional requests & HEAD method
+	http.ServeContent(ctx.Response(), ctx.Request(), name, config.ModTime, reader)
+
+	log.
+		Info().
+		Ctx(Context(ctx)).
+		Int("status", ctx.Response().Status).
+		Str("method", ctx.Request().Method).
+		Msg(ctx.Request().RequestURI)
+
+	return nil
+}
+
+// downloadReader converts content to [io.ReadSeeker] and fills config gaps by content info
+func downloadReader(content any, config *DownloadConfig) (io.ReadSeeker, io.Closer, error) {
+	switch value := content.(type) {
+	case []byte:
+		if config.ETag == "" {
+			hash := sha256.Sum256(value)
+			config.ETag = strconv.Quote(hex.EncodeToString(hash[:16]))
+		}
+
+		return bytes.NewReader(value), nil, nil
+	case fs.File:
+		info, err := value.Stat()
+		if err != nil {
+			return nil, value, err
+		}
+
+		if config.ModTime.IsZero() {
+			config.ModTime = info.ModTime()
+		}
+
+		if config.ETag == "" {
+			size := strconv.FormatInt(info.Size(), 16)
+			modTime := strconv.FormatInt(info.ModTime().UnixNano(), 16)
+			config.ETag = strconv.Quote(size + "-" + modTime)
+		}
+
+		if seeker, ok := value.(io.ReadSeeker); ok {
+			return seeker, value, nil
+		}
+
+		// file could not seek, so read it to memory
+		blob, err := io.ReadAll(value)
+		if err != nil {
+			return nil, value, err
+		}
+
+		return bytes.NewReader(blob), value, nil
+	case io.ReadSeeker:
+		return value, nil, nil
+	default:
+		return nil, nil, ErrDownloadContent.SetError(errors.New("content must be []byte, io.ReadSeeker or fs.File"))
+	}
+}
+
+// ContentDisposition returns Content-Disposition header value by RFC 6266:
+// ASCII fallback "filename" parameter and UTF-8 "filename*" parameter.
+func ContentDisposition(name string, inline bool) string {
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return disposition
+	}
+
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	if fallback == name {
+		return disposition + `; filename="` + name + `"`
+	}
+
+	return disposition + `; filename="` + fallback + `"; filename*=UTF-8''` + encodeRFC5987(name)
+}
+
+// encodeRFC5987 percent-encodes all bytes except RFC 5987 "attr-char"
+func encodeRFC5987(value string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isAttrChar(c) {
+			builder.WriteByte(c)
+			continue
+		}
+
+		builder.WriteByte('%')
+		builder.WriteByte(hexDigits[c>>4])
+		builder.WriteByte(hexDigits[c&0x0f])
+	}
+
+	return builder.String()
+}
+
+func isAttrChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	default:
+		return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+	}
+}
@@ -0,0 +1,142 @@
+package echox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func serveDownload(t *testing.T, method string, headers map[string]string, content func() any, cfg ...DownloadConfig) *httptest.ResponseRecorder {
+	t.Helper()
+
+	handler := echo.New()
+	handler.Any("/report", func(ctx echo.Context) error {
+		return Download(ctx, "отчёт 2024.txt", content(), cfg...)
+	})
+
+	request := httptest.NewRequest(method, "/report", nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestDownload(t *testing.T) {
+	content := func() any { return []byte("hello world") }
+
+	full := serveDownload(t, http.MethodGet, nil, content)
+	etag := full.Header().Get("ETag")
+	if full.Code != http.StatusOK || full.Body.String() != "hello world" || etag == "" {
+		t.Fatalf("status = %d, etag = %q, body = %q", full.Code, etag, full.Body)
+	}
+
+	if disposition := full.Header().Get(echo.HeaderContentDisposition); !strings.Contains(disposition, "filename*=UTF-8''") {
+		t.Fatalf("content disposition = %q", disposition)
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		headers      map[string]string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"range", http.MethodGet, map[string]string{"Range": "bytes=0-4"}, http.StatusPartialContent, "hello", "bytes 0-4/11"},
+		{"suffix range", http.MethodGet, map[string]string{"Range": "bytes=-5"}, http.StatusPartialContent, "world", "bytes 6-10/11"},
+		{"unsatisfiable range", http.MethodGet, map[string]string{"Range": "bytes=20-30"}, http.StatusRequestedRangeNotSatisfiable, "", "bytes */11"},
+		{"if-range matches", http.MethodGet, map[string]string{"Range": "bytes=0-4", "If-Range": etag}, http.StatusPartialContent, "hello", "bytes 0-4/11"},
+		{"if-range changed", http.MethodGet, map[string]string{"Range": "bytes=0-4", "If-Range": `"old"`}, http.StatusOK, "hello world", ""},
+		{"if-none-match", http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", ""},
+		{"if-none-match changed", http.MethodGet, map[string]string{"If-None-Match": `"old"`}, http.StatusOK, "hello world", ""},
+		{"head", http.MethodHead, nil, http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serveDownload(t, tt.method, tt.headers, content)
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.status)
+			}
+
+			if tt.status != http.StatusRequestedRangeNotSatisfiable && recorder.Body.String() != tt.body {
+				t.Fatalf("body = %q, want %q", recorder.Body, tt.body)
+			}
+
+			if contentRange := recorder.Header().Get("Content-Range"); contentRange != tt.contentRange {
+				t.Fatalf("content range = %q, want %q", contentRange, tt.contentRange)
+			}
+		})
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("hello world"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	content := func() any {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return file
+	}
+
+	full := serveDownload(t, http.MethodGet, nil, content)
+	if full.Code != http.StatusOK || full.Header().Get("Last-Modified") != modTime.Format(http.TimeFormat) {
+		t.Fatalf("status = %d, last modified = %q", full.Code, full.Header().Get("Last-Modified"))
+	}
+
+	notModified := serveDownload(t, http.MethodGet, map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)}, content)
+	if notModified.Code != http.StatusNotModified {
+		t.Fatalf("status = %d, want %d", notModified.Code, http.StatusNotModified)
+	}
+
+	multipart := serveDownload(t, http.MethodGet, map[string]string{"Range": "bytes=0-1,6-7"}, content)
+	if multipart.Code != http.StatusPartialContent || !strings.HasPrefix(multipart.Header().Get(echo.HeaderContentType), "multipart/byteranges") {
+		t.Fatalf("status = %d, content type = %q", multipart.Code, multipart.Header().Get(echo.HeaderContentType))
+	}
+}
+
+func TestDownloadInvalidContent(t *testing.T) {
+	recorder := serveDownload(t, http.MethodGet, nil, func() any { return "hello" })
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name   string
+		inline bool
+		want   string
+	}{
+		{"report.pdf", false, `attachment; filename="report.pdf"`},
+		{"report.pdf", true, `inline; filename="report.pdf"`},
+		{`../a"b.txt`, false, `attachment; filename="a_b.txt"; filename*=UTF-8''a%22b.txt`},
+		{"отчёт.txt", false, `attachment; filename="_____.txt"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D1%91%D1%82.txt`},
+	}
+
+	for _, tt := range tests {
+		if got := ContentDisposition(tt.name, tt.inline); got != tt.want {
+			t.Errorf("ContentDisposition(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
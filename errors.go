@@ -2,12 +2,128 @@ package echox
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/boostgo/errorx"
 	"github.com/boostgo/httpx"
 	"github.com/labstack/echo/v4"
 )
 
+var (
+	ErrReadRequestBody     = errorx.New("request_read_body").SetError(errorx.ErrBadRequest)
+	ErrBufferRequestBody   = errorx.New("request_buffer_body").SetError(errorx.ErrInternal)
+	ErrRequestBodyTooLarge = errorx.New("request_body_too_large").SetError(errorx.ErrEntityTooLarge)
+
+	ErrMultipartInvalid         = errorx.New("multipart_invalid").SetError(errorx.ErrBadRequest)
+	ErrMultipartTooLarge        = errorx.New("multipart_too_large").SetError(errorx.ErrEntityTooLarge)
+	ErrMultipartFileTooLarge    = errorx.New("multipart_file_too_large").SetError(errorx.ErrEntityTooLarge)
+	ErrMultipartFieldTooLarge   = errorx.New("multipart_field_too_large").SetError(errorx.ErrEntityTooLarge)
+	ErrMultipartTooManyFiles    = errorx.New("multipart_too_many_files").SetError(errorx.ErrBadRequest)
+	ErrMultipartTooManyFields   = errorx.New("multipart_too_many_fields").SetError(errorx.ErrBadRequest)
+	ErrMultipartFieldNotAllowed = errorx.New("multipart_field_not_allowed").SetError(errorx.ErrBadRequest)
+	ErrMultipartSave            = errorx.New("multipart_save")
+
+	ErrUploadTypeNotAllowed = errorx.New("upload_type_not_allowed").SetError(errorx.ErrUnsupportedMediaType)
+	ErrUploadInvalid        = errorx.New("upload_invalid").SetError(errorx.ErrUnprocessableEntity)
+
+	ErrTusUnsupportedVersion = errorx.New("tus_unsupported_version").SetError(errorx.ErrPreconditionFailed)
+	ErrTusInvalidLength      = errorx.New("tus_invalid_length").SetError(errorx.ErrBadRequest)
+	ErrTusInvalidOffset      = errorx.New("tus_invalid_offset").SetError(errorx.ErrBadRequest)
+	ErrTusInvalidMetadata    = errorx.New("tus_invalid_metadata").SetError(errorx.ErrBadRequest)
+	ErrTusInvalidContentType = errorx.New("tus_invalid_content_type").SetError(errorx.ErrUnsupportedMediaType)
+	ErrTusOffsetMismatch     = errorx.New("tus_offset_mismatch").SetError(errorx.ErrConflict)
+	ErrTusUploadNotFound     = errorx.New("tus_upload_not_found").SetError(errorx.ErrNotFound)
+	ErrTusUploadExpired      = errorx.New("tus_upload_expired").SetError(errorx.ErrGone)
+	ErrTusUploadLocked       = errorx.New("tus_upload_locked").SetError(errorx.ErrLocked)
+
+	ErrDownloadContent = errorx.New("download_content").SetError(errorx.ErrInternal)
+
+	ErrExportFormat = errorx.New("export_format").SetError(errorx.ErrBadRequest)
+	ErrExportRows   = errorx.New("export_rows").SetError(errorx.ErrInternal)
+
+	ErrIdempotencyKeyRequired = errorx.New("idempotency_key_required").SetError(errorx.ErrBadRequest)
+	ErrIdempotencyKeyInvalid  = errorx.New("idempotency_key_invalid").SetError(errorx.ErrBadRequest)
+	ErrIdempotencyInFlight    = errorx.New("idempotency_in_flight").SetError(errorx.ErrConflict)
+	ErrIdempotencyKeyReused   = errorx.New("idempotency_key_reused").SetError(errorx.ErrUnprocessableEntity)
+
+	ErrRateLimitExceeded = errorx.New("rate_limit_exceeded").SetError(errorx.ErrTooManyRequests)
+	ErrOverloaded        = errorx.New("overloaded").SetError(errorx.ErrServiceUnavailable)
+	ErrHandlerTimeout    = errorx.New("handler_timeout").SetError(errorx.ErrTimeout)
+
+	ErrCacheInvalidate = errorx.New("cache_invalidate").SetError(errorx.ErrInternal)
+	ErrCachePurgeEmpty = errorx.New("cache_purge_empty").SetError(errorx.ErrBadRequest)
+
+	ErrJWKS              = errorx.New("jwks").SetError(errorx.ErrInternal)
+	ErrJWKSKeyNotFound   = errorx.New("jwks_key_not_found").SetError(errorx.ErrUnauthorized)
+	ErrTokenMissing      = errorx.New("token_missing").SetError(errorx.ErrUnauthorized)
+	ErrTokenInvalid      = errorx.New("token_invalid").SetError(errorx.ErrUnauthorized)
+	ErrTokenExpired      = errorx.New("token_expired").SetError(errorx.ErrUnauthorized)
+	ErrInsufficientScope = errorx.New("insufficient_scope").SetError(errorx.ErrForbidden)
+
+	ErrIntrospection = errorx.New("introspection").SetError(errorx.ErrInternal)
+
+	ErrAPIKeyMissing = errorx.New("api_key_missing").SetError(errorx.ErrUnauthorized)
+	ErrAPIKeyInvalid = errorx.New("api_key_invalid").SetError(errorx.ErrUnauthorized)
+	ErrAPIKeyExpired = errorx.New("api_key_expired").SetError(errorx.ErrUnauthorized)
+	ErrAPIKeyStore   = errorx.New("api_key_store").SetError(errorx.ErrInternal)
+
+	ErrUnauthenticated  = errorx.New("unauthenticated").SetError(errorx.ErrUnauthorized)
+	ErrPermissionDenied = errorx.New("permission_denied").SetError(errorx.ErrForbidden)
+	ErrAuthorization    = errorx.New("authorization").SetError(errorx.ErrInternal)
+
+	ErrSession         = errorx.New("session").SetError(errorx.ErrInternal)
+	ErrSessionTooLarge = errorx.New("session_too_large").SetError(errorx.ErrInternal)
+
+	ErrCookieKeys     = errorx.New("cookie_keys").SetError(errorx.ErrInternal)
+	ErrCookieMissing  = errorx.New("cookie_missing").SetError(errorx.ErrBadRequest)
+	ErrCookieTampered = errorx.New("cookie_tampered").SetError(errorx.ErrBadRequest)
+
+	ErrCSRF               = errorx.New("csrf").SetError(errorx.ErrInternal)
+	ErrCSRFTokenMissing   = errorx.New("csrf_token_missing").SetError(errorx.ErrForbidden)
+	ErrCSRFTokenInvalid   = errorx.New("csrf_token_invalid").SetError(errorx.ErrForbidden)
+	ErrCSRFOriginMismatch = errorx.New("csrf_origin_mismatch").SetError(errorx.ErrForbidden)
+
+	ErrSecurityHeaders = errorx.New("security_headers").SetError(errorx.ErrInternal)
+	ErrCSPReport       = errorx.New("csp_report").SetError(errorx.ErrBadRequest)
+
+	ErrTrustedProxy = errorx.New("trusted_proxy").SetError(errorx.ErrInternal)
+
+	ErrIPFilter = errorx.New("ip_filter").SetError(errorx.ErrInternal)
+	ErrIPDenied = errorx.New("ip_denied").SetError(errorx.ErrForbidden)
+)
+
+// wrapError sets cause to provided error keeping its base error (e.g. errorx.ErrBadRequest),
+// so HTTP status code of the error is not lost
+func wrapError(err *errorx.Error, cause error) *errorx.Error {
+	return err.SetError(err.Inner(), cause)
+}
+
+// isError reports whether any error in err's tree is errorx error with the same message as target.
+// errors.Is could not be used for errors with inner error, because errorx compares message with full text of target
+func isError(err error, target *errorx.Error) bool {
+	var custom *errorx.Error
+	if errors.As(err, &custom) && custom.Message() == target.Message() {
+		return true
+	}
+
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() error }:
+		return wrapped.Unwrap() != nil && isError(wrapped.Unwrap(), target)
+	case interface{ Unwrap() []error }:
+		for _, inner := range wrapped.Unwrap() {
+			if isError(inner, target) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 type httpErrorContext struct {
 	Message     string `json:"message"`
 	Accept      string `json:"accept"`
@@ -40,3 +156,173 @@ func newRouteNotFoundError(request *http.Request) error {
 		URL:    request.RequestURI,
 	})
 }
+
+type requestBodyTooLargeContext struct {
+	Limit int64 `json:"limit"`
+}
+
+func newRequestBodyTooLargeError(limit int64) error {
+	return ErrRequestBodyTooLarge.SetData(requestBodyTooLargeContext{
+		Limit: limit,
+	})
+}
+
+type multipartLimitContext struct {
+	Field string `json:"field,omitempty"`
+	Limit int64  `json:"limit"`
+}
+
+func newMultipartLimitError(err *errorx.Error, field string, limit int64) error {
+	return err.SetData(multipartLimitContext{
+		Field: field,
+		Limit: limit,
+	})
+}
+
+type multipartFieldContext struct {
+	Field string `json:"field"`
+}
+
+func newMultipartFieldNotAllowedError(field string) error {
+	return ErrMultipartFieldNotAllowed.SetData(multipartFieldContext{
+		Field: field,
+	})
+}
+
+type uploadContext struct {
+	Field       string   `json:"field"`
+	Filename    string   `json:"filename"`
+	ContentType string   `json:"content_type"`
+	Reason      string   `json:"reason"`
+	Allowed     []string `json:"allowed,omitempty"`
+}
+
+func newUploadError(err *errorx.Error, file *FormFile, reason string, allowed []string) error {
+	return err.SetData(uploadContext{
+		Field:       file.Field,
+		Filename:    file.Filename,
+		ContentType: file.DetectedContentType,
+		Reason:      reason,
+		Allowed:     allowed,
+	})
+}
+
+type tusOffsetContext struct {
+	Expected int64 `json:"expected"`
+	Actual   int64 `json:"actual"`
+}
+
+func newTusOffsetMismatchError(expected, actual int64) error {
+	return ErrTusOffsetMismatch.SetData(tusOffsetContext{
+		Expected: expected,
+		Actual:   actual,
+	})
+}
+
+type exportFormatContext struct {
+	Format  string   `json:"format"`
+	Allowed []string `json:"allowed"`
+}
+
+func newExportFormatError(format string) error {
+	return ErrExportFormat.SetData(exportFormatContext{
+		Format:  format,
+		Allowed: []string{ExportFormatJSON, ExportFormatCSV, ExportFormatXLSX},
+	})
+}
+
+type idempotencyContext struct {
+	Key string `json:"key"`
+}
+
+func newIdempotencyError(err *errorx.Error, key string) error {
+	return err.SetData(idempotencyContext{
+		Key: key,
+	})
+}
+
+type rateLimitContext struct {
+	Limit      int   `json:"limit"`
+	RetryAfter int64 `json:"retry_after"`
+}
+
+func newRateLimitError(result RateLimitResult) error {
+	return ErrRateLimitExceeded.SetData(rateLimitContext{
+		Limit:      result.Limit,
+		RetryAfter: int64(math.Ceil(result.RetryAfter.Seconds())),
+	})
+}
+
+type handlerTimeoutContext struct {
+	Timeout string `json:"timeout"`
+}
+
+func newHandlerTimeoutError(timeout time.Duration) error {
+	return ErrHandlerTimeout.SetData(handlerTimeoutContext{
+		Timeout: timeout.String(),
+	})
+}
+
+type panicContext struct {
+	Panic string   `json:"panic"`
+	Stack []string `json:"stack"`
+}
+
+func newPanicError(value any, stack []byte) error {
+	return errorx.ErrPanicRecover.SetData(panicContext{
+		Panic: fmt.Sprint(value),
+		Stack: strings.Split(strings.TrimSpace(string(stack)), "\n"),
+	})
+}
+
+type tokenContext struct {
+	Reason string `json:"reason"`
+}
+
+func newTokenError(err *errorx.Error, reason error) error {
+	return err.SetData(tokenContext{
+		Reason: reason.Error(),
+	})
+}
+
+type scopeContext struct {
+	Missing []string `json:"missing"`
+}
+
+func newScopeError(missing []string) error {
+	return ErrInsufficientScope.SetData(scopeContext{
+		Missing: missing,
+	})
+}
+
+type apiKeyContext struct {
+	ID string `json:"id"`
+}
+
+func newAPIKeyError(err *errorx.Error, id string) error {
+	return err.SetData(apiKeyContext{
+		ID: id,
+	})
+}
+
+type permissionContext struct {
+	Missing []string `json:"missing"`
+	Any     bool     `json:"any,omitempty"`
+}
+
+func newPermissionDeniedError(missing []string, anyOf bool) error {
+	return ErrPermissionDenied.SetData(permissionContext{
+		Missing: missing,
+		Any:     anyOf,
+	})
+}
+
+type csrfOriginContext struct {
+	Origin string `json:"origin"`
+}
+
+func newCSRFOriginError(origin string) error {
+	return ErrCSRFOriginMismatch.SetData(csrfOriginContext{
+		Origin: origin,
+	})
+}
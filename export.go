@@ -0,0 +1,462 @@
+package echox
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boostgo/httpx"
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+
+	exportTag         = "export"
+	exportFormatParam = "format"
+	defaultExportName = "export"
+	defaultSheetName  = "Sheet1"
+	maxSheetNameSize  = 31
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// ExportConfig describes CSV & XLSX exports.
+type ExportConfig struct {
+	// Comma is CSV fields delimiter. Default is ','
+	Comma rune
+	// BOM adds UTF-8 byte order mark to the CSV beginning (helps Excel to detect encoding)
+	BOM bool
+	// SheetName is XLSX sheet name. Default is "Sheet1".
+	// Characters not allowed by Excel are replaced by "_" and name is cut to 31 characters
+	SheetName string
+}
+
+func newExportConfig(cfg ...ExportConfig) ExportConfig {
+	var config ExportConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.Comma == 0 {
+		config.Comma = ','
+	}
+
+	config.SheetName = exportSheetName(config.SheetName)
+	return config
+}
+
+// exportSheetName replaces characters not allowed in Excel sheet names and cuts name to 31 characters
+func exportSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	if runes := []rune(name); len(runes) > maxSheetNameSize {
+		name = string(runes[:maxSheetNameSize])
+	}
+
+	// sheet name could not start or end with apostrophe
+	name = strings.Trim(name, "'")
+	if strings.TrimSpace(name) == "" {
+		return defaultSheetName
+	}
+
+	return name
+}
+
+type exportKind int
+
+const (
+	exportString exportKind = iota
+	exportNumber
+	exportBool
+	exportTime
+)
+
+// exportColumn is one export column described by "export" struct tag:
+//
+//	`export:"Header name,order=1,format=2006-01-02"`
+type exportColumn struct {
+	index  []int
+	header string
+	order  int
+	format string
+	kind   exportKind
+}
+
+// Export returns rows in format chosen by "format" query param: json (default), csv or xlsx.
+//
+// Rows must be structures (or pointers to structures) with "export" tags. Use [ExportSlice] to export slice
+func Export[T any](ctx echo.Context, name string, rows iter.Seq[T], cfg ...ExportConfig) error {
+	switch format := strings.ToLower(QueryParam(ctx, exportFormatParam).String()); format {
+	case "", ExportFormatJSON:
+		// empty export is rendered as empty array, not null
+		return Ok(ctx, append(make([]T, 0), slices.Collect(rows)...))
+	case ExportFormatCSV:
+		return ExportCSV(ctx, name, rows, cfg...)
+	case ExportFormatXLSX:
+		return ExportXLSX(ctx, name, rows, cfg...)
+	default:
+		return Error(ctx, newExportFormatError(format))
+	}
+}
+
+// ExportSlice is [Export] of rows slice
+func ExportSlice[T any](ctx echo.Context, name string, rows []T, cfg ...ExportConfig) error {
+	return Export(ctx, name, slices.Values(rows), cfg...)
+}
+
+// ExportCSVSlice is [ExportCSV] of rows slice
+func ExportCSVSlice[T any](ctx echo.Context, name string, rows []T, cfg ...ExportConfig) error {
+	return ExportCSV(ctx, name, slices.Values(rows), cfg...)
+}
+
+// ExportXLSXSlice is [ExportXLSX] of rows slice
+func ExportXLSXSlice[T any](ctx echo.Context, name string, rows []T, cfg ...ExportConfig) error {
+	return ExportXLSX(ctx, name, slices.Values(rows), cfg...)
+}
+
+// ExportCSV streams rows as CSV file. Columns are described by "export" tags:
+//
+//	type User struct {
+//		Name      string    `export:"Name,order=1"`
+//		Balance   float64   `export:"Balance,order=2,format=%.2f"`
+//		CreatedAt time.Time `export:"Created at,order=3,format=2006-01-02"`
+//	}
+//
+// Formats of numbers & strings are printf formats with one verb, formats of dates are Go time layouts.
+// String values which could be interpreted as formulas are escaped
+func ExportCSV[T any](ctx echo.Context, name string, rows iter.Seq[T], cfg ...ExportConfig) error {
+	config := newExportConfig(cfg...)
+
+	columns, err := exportColumns(reflect.TypeFor[T]())
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	writeExportHeaders(ctx, name, ExportFormatCSV, httpx.ContentTypeCSV+"; charset=utf-8")
+
+	if config.BOM {
+		if _, err = ctx.Response().Write([]byte("\xef\xbb\xbf")); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(ctx.Response())
+	writer.Comma = config.Comma
+
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = escapeCSVValue(column.header)
+	}
+
+	if err = writer.Write(record); err != nil {
+		return logExportError(ctx, err)
+	}
+
+	for row := range rows {
+		value := structValue(reflect.ValueOf(row))
+		for i, column := range columns {
+			record[i] = ""
+			if value.IsValid() {
+				record[i] = formatExportValue(fieldValue(value, column.index), column)
+			}
+		}
+
+		if err = writer.Write(record); err != nil {
+			return logExportError(ctx, err)
+		}
+	}
+
+	writer.Flush()
+	return logExportError(ctx, writer.Error())
+}
+
+// ExportXLSX streams rows as XLSX file with one sheet. Columns are described by "export" tags like in [ExportCSV].
+//
+// Numbers & dates are written as Excel numbers & dates. Number formats like "%.2f" and date formats
+// (Go time layouts) are converted to Excel number formats
+func ExportXLSX[T any](ctx echo.Context, name string, rows iter.Seq[T], cfg ...ExportConfig) error {
+	config := newExportConfig(cfg...)
+
+	columns, err := exportColumns(reflect.TypeFor[T]())
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	writeExportHeaders(ctx, name, ExportFormatXLSX, httpx.ContentTypeExcel)
+
+	writer := newXLSXWriter(ctx.Response(), config.SheetName, columns)
+	if err = writer.writeHeader(); err != nil {
+		return logExportError(ctx, err)
+	}
+
+	for row := range rows {
+		if err = writer.writeRow(structValue(reflect.ValueOf(row))); err != nil {
+			return logExportError(ctx, err)
+		}
+	}
+
+	return logExportError(ctx, writer.close())
+}
+
+func writeExportHeaders(ctx echo.Context, name, extension, contentType string) {
+	setTraceHeader(ctx)
+
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, ContentDisposition(exportFileName(name, extension), false))
+	ctx.Response().WriteHeader(http.StatusOK)
+}
+
+// logExportError logs error happened while streaming: response is already committed, so error could not be returned
+func logExportError(ctx echo.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	log.
+		Error().
+		Ctx(Context(ctx)).
+		Err(err).
+		Str("method", ctx.Request().Method).
+		Msg("Stream export")
+
+	return nil
+}
+
+// exportFileName removes path & unsafe characters from provided name and sets extension
+func exportFileName(name, extension string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	name = strings.TrimSuffix(name, "."+extension)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		name = defaultExportName
+	}
+
+	return name + "." + extension
+}
+
+func exportColumns(typ reflect.Type) ([]exportColumn, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil, ErrExportRows.SetError(errors.New("rows must be structures, got " + typ.String()))
+	}
+
+	columns := make([]exportColumn, 0, typ.NumField())
+	for _, field := range reflect.VisibleFields(typ) {
+		tag, ok := field.Tag.Lookup(exportTag)
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		column, err := parseExportTag(field, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, ErrExportRows.SetError(errors.New(typ.String() + " has no fields with export tag"))
+	}
+
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].order < columns[j].order
+	})
+
+	return columns, nil
+}
+
+func parseExportTag(field reflect.StructField, tag string) (exportColumn, error) {
+	parts := strings.Split(tag, ",")
+
+	column := exportColumn{
+		index:  field.Index,
+		header: strings.TrimSpace(parts[0]),
+		kind:   exportKindOf(field.Type),
+	}
+	if column.header == "" {
+		column.header = field.Name
+	}
+
+	for i := 1; i < len(parts); i++ {
+		key, value, _ := strings.Cut(parts[i], "=")
+		switch strings.TrimSpace(key) {
+		case "order":
+			order, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return exportColumn{}, ErrExportRows.SetError(errors.New("invalid order of field " + field.Name))
+			}
+
+			column.order = order
+		case "format":
+			// format is the last option, because it could contain commas
+			column.format = strings.Join(append([]string{value}, parts[i+1:]...), ",")
+			i = len(parts)
+		}
+	}
+
+	if column.format != "" && column.kind != exportTime && printfVerbs(column.format) != 1 {
+		return exportColumn{}, ErrExportRows.SetError(errors.New("format of field " + field.Name +
+			" must contain one printf verb, got " + strconv.Quote(column.format)))
+	}
+
+	return column, nil
+}
+
+// printfVerbs returns count of printf verbs in format ("%%" is not a verb)
+func printfVerbs(format string) int {
+	verbs := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+
+		verbs++
+	}
+
+	return verbs
+}
+
+func exportKindOf(typ reflect.Type) exportKind {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ == timeType {
+		return exportTime
+	}
+
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if typ.Implements(stringerType) {
+			return exportString
+		}
+
+		return exportNumber
+	case reflect.Bool:
+		return exportBool
+	default:
+		return exportString
+	}
+}
+
+// structValue dereferences pointers and returns invalid value for nil rows
+func structValue(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+
+		value = value.Elem()
+	}
+
+	return value
+}
+
+// fieldValue returns field by index, returns invalid value if any embedded pointer is nil
+func fieldValue(value reflect.Value, index []int) reflect.Value {
+	for i, idx := range index {
+		if i > 0 {
+			value = structValue(value)
+			if !value.IsValid() {
+				return value
+			}
+		}
+
+		value = value.Field(idx)
+	}
+
+	return structValue(value)
+}
+
+func formatExportValue(value reflect.Value, column exportColumn) string {
+	if !value.IsValid() {
+		return ""
+	}
+
+	switch column.kind {
+	case exportTime:
+		moment := value.Interface().(time.Time)
+		if moment.IsZero() {
+			return ""
+		}
+
+		if column.format != "" {
+			return moment.Format(column.format)
+		}
+
+		return moment.Format(time.RFC3339)
+	case exportNumber:
+		if column.format != "" {
+			return fmt.Sprintf(column.format, value.Interface())
+		}
+
+		if value.CanFloat() {
+			return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+		}
+
+		return fmt.Sprint(value.Interface())
+	case exportBool:
+		return strconv.FormatBool(value.Bool())
+	default:
+		return escapeCSVValue(exportText(value, column.format))
+	}
+}
+
+func exportText(value reflect.Value, format string) string {
+	if format != "" {
+		return fmt.Sprintf(format, value.Interface())
+	}
+
+	return fmt.Sprint(value.Interface())
+}
+
+// escapeCSVValue prevents CSV (formula) injection: values which start with formula characters are prefixed by quote
+func escapeCSVValue(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
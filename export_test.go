@@ -0,0 +1,185 @@
+package echox
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type exportUser struct {
+	Name      string    `export:"Name,order=1"`
+	Balance   float64   `export:"Balance,order=2,format=%.2f"`
+	CreatedAt time.Time `export:"Created at,order=3,format=2006-01-02"`
+	Active    bool      `export:"Active,order=4"`
+	Password  string
+}
+
+var exportUsers = []exportUser{
+	{Name: "Alice", Balance: 10.5, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Active: true},
+	{Name: "=SUM(A1)", Balance: 3, Password: "secret"},
+}
+
+func serveExport(query string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/users"+query, nil)
+	recorder := httptest.NewRecorder()
+	ctx := echo.New().NewContext(request, recorder)
+	if err := handler(ctx); err != nil {
+		ctx.Error(err)
+	}
+
+	return recorder
+}
+
+func TestExportJSON(t *testing.T) {
+	recorder := serveExport("", func(ctx echo.Context) error {
+		return ExportSlice(ctx, "users", []exportUser{})
+	})
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "[]") {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	recorder := serveExport("?format=pdf", func(ctx echo.Context) error {
+		return ExportSlice(ctx, "users", exportUsers)
+	})
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	recorder := serveExport("?format=csv", func(ctx echo.Context) error {
+		return ExportSlice(ctx, "../users", exportUsers, ExportConfig{Comma: ';', BOM: true})
+	})
+
+	want := "\xef\xbb\xbfName;Balance;Created at;Active\n" +
+		"Alice;10.50;2024-01-02;true\n" +
+		"'=SUM(A1);3.00;;false\n"
+	if recorder.Body.String() != want {
+		t.Fatalf("body = %q, want %q", recorder.Body, want)
+	}
+
+	if disposition := recorder.Header().Get(echo.HeaderContentDisposition); disposition != `attachment; filename="users.csv"` {
+		t.Fatalf("content disposition = %q", disposition)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	recorder := serveExport("?format=xlsx", func(ctx echo.Context) error {
+		return ExportSlice(ctx, "users", exportUsers, ExportConfig{SheetName: "Users: 2024/01"})
+	})
+
+	archive, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		entry, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(entry)
+		_ = entry.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[file.Name] = string(content)
+	}
+
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Users_ 2024_01"`) {
+		t.Fatalf("workbook = %s", files["xl/workbook.xml"])
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, cell := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Alice</t></is></c>`,
+		`<c r="B2" s="2"><v>10.5</v></c>`,
+		`<c r="C2" s="3"><v>45293</v></c>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("sheet has no cell %s", cell)
+		}
+	}
+
+	if !strings.Contains(files["xl/styles.xml"], `formatCode="yyyy-mm-dd"`) || !strings.Contains(files["xl/styles.xml"], `formatCode="0.00"`) {
+		t.Fatalf("styles = %s", files["xl/styles.xml"])
+	}
+}
+
+func TestExportColumnsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  reflect.Type
+	}{
+		{"not struct", reflect.TypeFor[string]()},
+		{"no tags", reflect.TypeFor[struct{ Name string }]()},
+		{"invalid order", reflect.TypeFor[struct {
+			Name string `export:"Name,order=first"`
+		}]()},
+		{"format without verb", reflect.TypeFor[struct {
+			Balance float64 `export:"Balance,format=USD"`
+		}]()},
+		{"format with two verbs", reflect.TypeFor[struct {
+			Balance float64 `export:"Balance,format=%d %d"`
+		}]()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exportColumns(tt.typ); !isError(err, ErrExportRows) {
+				t.Fatalf("expected export rows error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExportSheetName(t *testing.T) {
+	tests := map[string]string{
+		"":                                      defaultSheetName,
+		"Report [2024]":                         "Report _2024_",
+		"'quoted'":                              "quoted",
+		"   ":                                   defaultSheetName,
+		strings.Repeat("я", maxSheetNameSize+5): strings.Repeat("я", maxSheetNameSize),
+	}
+
+	for name, want := range tests {
+		if got := exportSheetName(name); got != want {
+			t.Errorf("exportSheetName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCellReference(t *testing.T) {
+	tests := []struct {
+		column int
+		want   string
+	}{
+		{0, "A1"},
+		{25, "Z1"},
+		{26, "AA1"},
+		{701, "ZZ1"},
+		{702, "AAA1"},
+	}
+
+	for _, tt := range tests {
+		if got := cellReference(tt.column, 1); got != tt.want {
+			t.Errorf("cellReference(%d) = %q, want %q", tt.column, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package echox
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+
+	"github.com/boostgo/contextx"
+	"github.com/boostgo/defaultx"
+	"github.com/boostgo/httpx"
+	"github.com/boostgo/validatex"
+	"github.com/labstack/echo/v4"
+)
+
+const fileTag = "file"
+
+var (
+	formFileType      = reflect.TypeOf((*FormFile)(nil))
+	formFileSliceType = reflect.TypeOf([]*FormFile(nil))
+)
+
+// FormFile is uploaded multipart form file with its metadata.
+type FormFile struct {
+	Field    string `json:"field"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	// ContentType is content type declared by client
+	ContentType string `json:"content_type"`
+	// DetectedContentType is content type sniffed from file content
+	DetectedContentType string `json:"detected_content_type"`
+
+	header *multipart.FileHeader
+}
+
+func newFormFile(field string, header *multipart.FileHeader) (*FormFile, error) {
+	file := &FormFile{
+		Field:       field,
+		Filename:    header.Filename,
+		Size:        header.Size,
+		ContentType: header.Header.Get(echo.HeaderContentType),
+		header:      header,
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	if file.DetectedContentType, err = sniffContentType(content, header.Size); err != nil {
+		return nil, wrapError(httpx.ErrReadFormFile, err)
+	}
+
+	return file, nil
+}
+
+// Open opens file content
+func (file *FormFile) Open() (multipart.File, error) {
+	content, err := file.header.Open()
+	if err != nil {
+		return nil, wrapError(httpx.ErrOpenFormFile, err)
+	}
+
+	return content, nil
+}
+
+// Bytes returns whole file content
+func (file *FormFile) Bytes() ([]byte, error) {
+	content, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return io.ReadAll(content)
+}
+
+// Header returns original multipart file header
+func (file *FormFile) Header() *multipart.FileHeader {
+	return file.header
+}
+
+// Files returns all uploaded files by provided form field name.
+//
+// Request body must be form data
+func Files(ctx echo.Context, name string) ([]*FormFile, error) {
+	if err := contextx.Validate(Context(ctx)); err != nil {
+		return nil, err
+	}
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return nil, wrapError(httpx.ErrReadFormFile, err)
+	}
+
+	headers := form.File[name]
+	if len(headers) == 0 {
+		return nil, wrapError(httpx.ErrReadFormFile, http.ErrMissingFile)
+	}
+
+	files := make([]*FormFile, 0, len(headers))
+	for _, header := range headers {
+		file, err := newFormFile(name, header)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// FormValues returns all form values (url encoded or multipart form) as map with all values of every key.
+//
+// Notice: in this map no any files. Get them by [Files] function
+func FormValues(ctx echo.Context) (map[string][]httpx.Param, error) {
+	if err := contextx.Validate(Context(ctx)); err != nil {
+		return nil, err
+	}
+
+	values, err := ctx.FormParams()
+	if err != nil {
+		return nil, wrapError(httpx.ErrParseRequestBody, err)
+	}
+
+	exportMap := make(map[string][]httpx.Param, len(values))
+	for key, keyValues := range values {
+		params := make([]httpx.Param, 0, len(keyValues))
+		for _, value := range keyValues {
+			params = append(params, httpx.NewParam(value))
+		}
+
+		exportMap[key] = params
+	}
+
+	return exportMap, nil
+}
+
+// ParseMultipartForm parses multipart form values (by "form" tags) and files (by "file" tags) to
+// provided export object (must be pointer to structure object).
+//
+// Fields with "file" tag must be *[FormFile] or []*[FormFile] type.
+//
+// After success parsing, run structure validation (for "validate" tags) and defaults setting (for "default" tags)
+func ParseMultipartForm(ctx echo.Context, export any) error {
+	if err := contextx.Validate(Context(ctx)); err != nil {
+		return err
+	}
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return wrapError(httpx.ErrParseRequestBody, err)
+	}
+
+	if err = (&echo.DefaultBinder{}).BindBody(ctx, export); err != nil {
+		return newParseRequestBodyError(ctx, err)
+	}
+
+	if err = bindFormFiles(export, form.File); err != nil {
+		return err
+	}
+
+	if err = validatex.Get().Struct(export); err != nil {
+		return err
+	}
+
+	return defaultx.Set(export)
+}
+
+func bindFormFiles(export any, files map[string][]*multipart.FileHeader) error {
+	value := reflect.ValueOf(export)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return httpx.ErrParseRequestBody.SetError(errors.New("export must be pointer to struct"))
+	}
+
+	value = value.Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := field.Tag.Get(fileTag)
+		if name == "" || !value.Field(i).CanSet() {
+			continue
+		}
+
+		headers := files[name]
+		if len(headers) == 0 {
+			continue
+		}
+
+		formFiles := make([]*FormFile, 0, len(headers))
+		for _, header := range headers {
+			file, err := newFormFile(name, header)
+			if err != nil {
+				return err
+			}
+
+			formFiles = append(formFiles, file)
+		}
+
+		switch field.Type {
+		case formFileType:
+			value.Field(i).Set(reflect.ValueOf(formFiles[0]))
+		case formFileSliceType:
+			value.Field(i).Set(reflect.ValueOf(formFiles))
+		default:
+			return httpx.ErrParseRequestBody.SetError(errors.New("field " + field.Name + " must be *FormFile or []*FormFile"))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package echox
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/boostgo/httpx"
+	"github.com/labstack/echo/v4"
+)
+
+// newMultipartContext creates context with multipart form of provided values & files (field -> file name -> content)
+func newMultipartContext(t *testing.T, values map[string][]string, files map[string]map[string]string) echo.Context {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, fieldValues := range values {
+		for _, value := range fieldValues {
+			if err := writer.WriteField(name, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for name, fieldFiles := range files {
+		for filename, content := range fieldFiles {
+			part, err := writer.CreateFormFile(name, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = part.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/", &body)
+	request.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	return echo.New().NewContext(request, httptest.NewRecorder())
+}
+
+func newBodyContext(contentType, body string) echo.Context {
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, contentType)
+	return echo.New().NewContext(request, httptest.NewRecorder())
+}
+
+func assertBadRequest(t *testing.T, err error) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if status := httpx.StatusCodeByError(err); status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusBadRequest, err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	ctx := newMultipartContext(t, nil, map[string]map[string]string{
+		"file": {"a.txt": "hello", "b.png": "\x89PNG\r\n\x1a\n0000"},
+	})
+
+	files, err := Files(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("files = %d, want 2", len(files))
+	}
+
+	detected := make(map[string]string)
+	for _, file := range files {
+		if file.Field != "file" {
+			t.Errorf("field = %q", file.Field)
+		}
+
+		detected[file.Filename] = file.DetectedContentType
+	}
+
+	if !strings.HasPrefix(detected["a.txt"], "text/plain") || detected["b.png"] != "image/png" {
+		t.Fatalf("detected content types = %v", detected)
+	}
+}
+
+func TestFormFileBytes(t *testing.T) {
+	ctx := newMultipartContext(t, nil, map[string]map[string]string{"file": {"a.txt": "hello"}})
+
+	files, err := Files(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := files[0].Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "hello" || files[0].Size != 5 || files[0].Header() == nil {
+		t.Fatalf("content = %q, size = %d", content, files[0].Size)
+	}
+}
+
+func TestFilesInvalid(t *testing.T) {
+	t.Run("missing field", func(t *testing.T) {
+		ctx := newMultipartContext(t, map[string][]string{"title": {"report"}}, nil)
+
+		_, err := Files(ctx, "file")
+		assertBadRequest(t, err)
+	})
+
+	t.Run("not multipart", func(t *testing.T) {
+		_, err := Files(newBodyContext(echo.MIMEApplicationJSON, "{}"), "file")
+		assertBadRequest(t, err)
+	})
+}
+
+func TestFormValues(t *testing.T) {
+	t.Run("url encoded", func(t *testing.T) {
+		body := url.Values{"tag": {"a", "b"}, "title": {"report"}}.Encode()
+
+		values, err := FormValues(newBodyContext(echo.MIMEApplicationForm, body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(values["tag"]) != 2 || values["tag"][1].String() != "b" || values["title"][0].String() != "report" {
+			t.Fatalf("values = %v", values)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		ctx := newMultipartContext(t, map[string][]string{"tag": {"a", "b"}}, map[string]map[string]string{
+			"file": {"a.txt": "hello"},
+		})
+
+		values, err := FormValues(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(values["tag"]) != 2 || len(values["file"]) != 0 {
+			t.Fatalf("values = %v", values)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := FormValues(newBodyContext(echo.MIMEMultipartForm+"; boundary=x", "--x\r\nContent-Disposition form-data\r\n\r\n"))
+		assertBadRequest(t, err)
+	})
+}
+
+type reportForm struct {
+	Title       string      `form:"title" validate:"required"`
+	Tags        []string    `form:"tag"`
+	Cover       *FormFile   `file:"cover"`
+	Attachments []*FormFile `file:"attachment"`
+}
+
+func TestParseMultipartForm(t *testing.T) {
+	ctx := newMultipartContext(t, map[string][]string{"title": {"report"}, "tag": {"a", "b"}}, map[string]map[string]string{
+		"cover":      {"cover.txt": "cover"},
+		"attachment": {"a.txt": "a", "b.txt": "b"},
+	})
+
+	var form reportForm
+	if err := ParseMultipartForm(ctx, &form); err != nil {
+		t.Fatal(err)
+	}
+
+	if form.Title != "report" || len(form.Tags) != 2 {
+		t.Fatalf("form = %+v", form)
+	}
+
+	if form.Cover == nil || form.Cover.Filename != "cover.txt" || len(form.Attachments) != 2 {
+		t.Fatalf("files = %+v, %+v", form.Cover, form.Attachments)
+	}
+}
+
+func TestParseMultipartFormInvalid(t *testing.T) {
+	t.Run("not multipart", func(t *testing.T) {
+		var form reportForm
+		assertBadRequest(t, ParseMultipartForm(newBodyContext(echo.MIMEApplicationJSON, "{}"), &form))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		ctx := newMultipartContext(t, map[string][]string{"tag": {"a"}}, nil)
+
+		var form reportForm
+		if err := ParseMultipartForm(ctx, &form); err == nil {
+			t.Fatal("expected validation error")
+		}
+	})
+
+	t.Run("wrong file field type", func(t *testing.T) {
+		ctx := newMultipartContext(t, nil, map[string]map[string]string{"cover": {"cover.txt": "cover"}})
+
+		var form struct {
+			Cover string `file:"cover"`
+		}
+		if err := ParseMultipartForm(ctx, &form); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
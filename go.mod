@@ -16,9 +16,11 @@ require (
 	github.com/boostgo/pagex v0.0.1
 	github.com/boostgo/trace v1.0.0
 	github.com/boostgo/validatex v1.0.0
+	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/google/uuid v1.6.0
 	github.com/labstack/echo/v4 v4.13.3
 	github.com/swaggo/echo-swagger v1.4.1
+	golang.org/x/sync v0.11.0
 )
 
 require (
@@ -0,0 +1,349 @@
+package echox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyLockTTL = time.Minute
+	maxIdempotencyKeyLength   = 255
+)
+
+// IdempotencyRecord is state of the request by idempotency key: in flight or completed with saved response.
+type IdempotencyRecord struct {
+	// Fingerprint is hash of request method, path, query & body
+	Fingerprint string      `json:"fingerprint"`
+	Completed   bool        `json:"completed"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// IdempotencyStore keeps idempotency records. Implementations must be safe for concurrent use
+// and Lock must be atomic (only one request could lock the key).
+type IdempotencyStore interface {
+	// Lock saves in-flight record by the key if there is no record yet and returns true.
+	// If record already exists, returns it and false
+	Lock(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, bool, error)
+	// Complete replaces in-flight record by completed record with saved response
+	Complete(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	// Unlock removes in-flight record, so request with the key could be retried
+	Unlock(ctx context.Context, key string) error
+}
+
+// IdempotencyConfig describes [IdempotencyMiddleware].
+type IdempotencyConfig struct {
+	// Store keeps records. Default is in-memory store created by [NewMemoryIdempotencyStore]
+	Store IdempotencyStore
+	// Header with idempotency key. Default is "Idempotency-Key"
+	Header string
+	// Methods which use idempotency keys. Default are POST & PATCH
+	Methods []string
+	// Required rejects requests without idempotency key
+	Required bool
+	// TTL is how long completed responses are kept. Default is 24 hours
+	TTL time.Duration
+	// LockTTL is how long in-flight record is kept if request never completes (e.g. service crashed).
+	// Default is 1 minute
+	LockTTL time.Duration
+	// Scope returns prefix of the keys, e.g. client or user ID, so different clients could use same keys
+	Scope func(ctx echo.Context) string
+	// MaxBodySize is maximum size in bytes of request body read for fingerprint. Bigger bodies are rejected
+	// with 413 status. Default is 10MB. Limit of [BodyBufferMiddleware] is used instead if it is set
+	MaxBodySize int64
+}
+
+func newIdempotencyConfig(cfg ...IdempotencyConfig) IdempotencyConfig {
+	var config IdempotencyConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryIdempotencyStore()
+	}
+
+	if config.Header == "" {
+		config.Header = IdempotencyKeyHeader
+	}
+
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	if config.TTL <= 0 {
+		config.TTL = defaultIdempotencyTTL
+	}
+
+	if config.LockTTL <= 0 {
+		config.LockTTL = defaultIdempotencyLockTTL
+	}
+
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultBodyMaxSize
+	}
+
+	return config
+}
+
+// IdempotencyMiddleware makes requests with "Idempotency-Key" header safe to retry.
+//
+// First request with the key is handled and its final response (status, headers & body) is saved to the store.
+// Retries with the same key & payload get saved response with "Idempotent-Replayed: true" header.
+//
+// While first request is in flight, retries get 409 Conflict. If the key is reused with different
+// request payload, returns 422 Unprocessable Entity.
+//
+// Responses with 5xx status codes and errors returned by handler are not saved, so request could be retried
+func IdempotencyMiddleware(cfg ...IdempotencyConfig) echo.MiddlewareFunc {
+	config := newIdempotencyConfig(cfg...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+			if !slices.Contains(config.Methods, request.Method) {
+				return next(ctx)
+			}
+
+			key := strings.TrimSpace(request.Header.Get(config.Header))
+			if key == "" {
+				if config.Required {
+					return Error(ctx, ErrIdempotencyKeyRequired)
+				}
+
+				return next(ctx)
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				return Error(ctx, newIdempotencyError(ErrIdempotencyKeyInvalid, key))
+			}
+
+			body, err := peekBody(ctx, config.MaxBodySize)
+			if err != nil {
+				return Error(ctx, err)
+			}
+
+			storeKey := key
+			if config.Scope != nil {
+				storeKey = config.Scope(ctx) + ":" + key
+			}
+
+			record := IdempotencyRecord{
+				Fingerprint: idempotencyFingerprint(request, body),
+				CreatedAt:   time.Now(),
+			}
+
+			stored, locked, err := config.Store.Lock(Context(ctx), storeKey, record, config.LockTTL)
+			if err != nil {
+				return Error(ctx, err)
+			}
+
+			if !locked {
+				switch {
+				case stored.Fingerprint != record.Fingerprint:
+					return Error(ctx, newIdempotencyError(ErrIdempotencyKeyReused, key))
+				case !stored.Completed:
+					ctx.Response().Header().Set(echo.HeaderRetryAfter, "1")
+					return Error(ctx, newIdempotencyError(ErrIdempotencyInFlight, key))
+				default:
+					return replayIdempotentResponse(ctx, stored)
+				}
+			}
+
+			return handleIdempotent(ctx, next, config, storeKey, record)
+		}
+	}
+}
+
+func handleIdempotent(
+	ctx echo.Context,
+	next echo.HandlerFunc,
+	config IdempotencyConfig,
+	key string,
+	record IdempotencyRecord,
+) error {
+	// request context could be canceled after response, but record must be saved anyway
+	storeCtx := context.WithoutCancel(Context(ctx))
+
+	response := ctx.Response()
+	recorder := newResponseRecorder(response.Writer)
+	response.Writer = recorder
+	defer func() {
+		response.Writer = recorder.inner
+	}()
+
+	// key is unlocked if response is not saved, including handler panic, so request could be retried.
+	// Panic is not recovered here, so recover middleware gets the original stack
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+
+		if err := config.Store.Unlock(storeCtx, key); err != nil {
+			logIdempotencyError(ctx, err, "Unlock idempotency key")
+		}
+	}()
+
+	handlerErr := next(ctx)
+	if handlerErr != nil || !response.Committed || recorder.Status() >= http.StatusInternalServerError {
+		return handlerErr
+	}
+
+	completed = true
+	record.Completed = true
+	record.Status = recorder.Status()
+	record.Header = response.Header().Clone()
+	record.Header.Del(TraceKey)
+	record.Header.Del(echo.HeaderXRequestID)
+	record.Body = recorder.body.Bytes()
+
+	if err := config.Store.Complete(storeCtx, key, record, config.TTL); err != nil {
+		logIdempotencyError(ctx, err, "Complete idempotency key")
+	}
+
+	return nil
+}
+
+func replayIdempotentResponse(ctx echo.Context, record IdempotencyRecord) error {
+	header := ctx.Response().Header()
+	for name, values := range record.Header {
+		header[name] = slices.Clone(values)
+	}
+
+	setTraceHeader(ctx)
+	header.Set(IdempotencyReplayedHeader, "true")
+
+	ctx.Response().WriteHeader(record.Status)
+	if _, err := ctx.Response().Write(record.Body); err != nil {
+		return err
+	}
+
+	log.
+		Info().
+		Ctx(Context(ctx)).
+		Int("status", record.Status).
+		Str("method", ctx.Request().Method).
+		Msg(ctx.Request().RequestURI)
+
+	return nil
+}
+
+// idempotencyFingerprint returns hash of request method, path, query & body
+func idempotencyFingerprint(request *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(request.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(request.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write([]byte(request.URL.RawQuery))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func logIdempotencyError(ctx echo.Context, err error, message string) {
+	log.
+		Error().
+		Ctx(Context(ctx)).
+		Err(err).
+		Str("method", ctx.Request().Method).
+		Msg(message)
+}
+
+// MemoryIdempotencyStore is in-memory [IdempotencyStore]. It works only within one service instance.
+type MemoryIdempotencyStore struct {
+	records   map[string]memoryIdempotencyRecord
+	lastSweep time.Time
+	mx        sync.Mutex
+}
+
+type memoryIdempotencyRecord struct {
+	record    IdempotencyRecord
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore creates [MemoryIdempotencyStore]
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]memoryIdempotencyRecord),
+	}
+}
+
+func (store *MemoryIdempotencyStore) Lock(
+	_ context.Context,
+	key string,
+	record IdempotencyRecord,
+	ttl time.Duration,
+) (IdempotencyRecord, bool, error) {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	now := time.Now()
+	store.sweep(now)
+
+	if existing, ok := store.records[key]; ok && now.Before(existing.expiresAt) {
+		return existing.record, false, nil
+	}
+
+	store.records[key] = memoryIdempotencyRecord{
+		record:    record,
+		expiresAt: now.Add(ttl),
+	}
+
+	return IdempotencyRecord{}, true, nil
+}
+
+func (store *MemoryIdempotencyStore) Complete(
+	_ context.Context,
+	key string,
+	record IdempotencyRecord,
+	ttl time.Duration,
+) error {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	store.records[key] = memoryIdempotencyRecord{
+		record:    record,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	return nil
+}
+
+func (store *MemoryIdempotencyStore) Unlock(_ context.Context, key string) error {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	delete(store.records, key)
+	return nil
+}
+
+// sweep removes expired records not often than once a minute
+func (store *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < time.Minute {
+		return
+	}
+
+	store.lastSweep = now
+	for key, record := range store.records {
+		if !now.Before(record.expiresAt) {
+			delete(store.records, key)
+		}
+	}
+}
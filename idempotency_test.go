@@ -0,0 +1,59 @@
+package echox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	handler := echo.New()
+	handler.POST("/payments", func(ctx echo.Context) error {
+		calls.Add(1)
+		return ctx.String(http.StatusCreated, "payment "+ctx.QueryParam("amount"))
+	}, IdempotencyMiddleware())
+
+	send := func(key, query, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/payments"+query, strings.NewReader(body))
+		request.Header.Set(IdempotencyKeyHeader, key)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	first := send("key-1", "?amount=10", "{}")
+	if first.Code != http.StatusCreated || first.Body.String() != "payment 10" {
+		t.Fatalf("status = %d, body = %s", first.Code, first.Body)
+	}
+
+	replay := send("key-1", "?amount=10", "{}")
+	if replay.Code != http.StatusCreated || replay.Body.String() != "payment 10" || replay.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Fatalf("replay status = %d, body = %s", replay.Code, replay.Body)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"different query", "?amount=1000", "{}"},
+		{"different body", "?amount=10", `{"note":"x"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if recorder := send("key-1", tt.query, tt.body); recorder.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d", recorder.Code, http.StatusUnprocessableEntity)
+			}
+		})
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,351 @@
+package echox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	introspectionKey = "token-introspection"
+
+	PrincipalSourceIntrospection = "introspection"
+
+	defaultIntrospectionTimeout  = 10 * time.Second
+	defaultIntrospectionCacheTTL = time.Minute
+	maxIntrospectionSize         = 1 << 20 // 1MB
+)
+
+// IntrospectionResult is RFC 7662 token introspection response.
+type IntrospectionResult struct {
+	Active    bool      `json:"active"`
+	Subject   string    `json:"subject,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	ClientID  string    `json:"client_id,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	TokenType string    `json:"token_type,omitempty"`
+	Audience  []string  `json:"audience,omitempty"`
+	Issuer    string    `json:"issuer,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// Claims are all response fields
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// IntrospectionConfig describes [IntrospectionMiddleware].
+type IntrospectionConfig struct {
+	// URL of introspection endpoint. Required
+	URL string
+	// ClientID & ClientSecret authenticate resource server at introspection endpoint (HTTP Basic auth)
+	ClientID     string
+	ClientSecret string
+	// Client is HTTP client for introspection requests. Requests are limited by 10 seconds regardless of the client
+	Client *http.Client
+	// Scopes are required token scopes of all routes
+	Scopes []string
+	// Routes are required scopes of routes by keys like "GET /users/:id" or "/users/:id" (any method).
+	// Route scopes replace Scopes
+	Routes map[string][]string
+	// MaxCacheTTL limits how long active tokens are cached. By default, tokens are cached until expiration
+	// and tokens without expiration are cached for 1 minute. Negative value disables cache
+	MaxCacheTTL time.Duration
+	// Query is query param with token (if token is not in "Authorization" header)
+	Query string
+	// Cookie is cookie with token (if token is not in "Authorization" header)
+	Cookie string
+	// Realm is "WWW-Authenticate" header realm
+	Realm string
+}
+
+func newIntrospectionConfig(cfg IntrospectionConfig) IntrospectionConfig {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultIntrospectionTimeout}
+	}
+
+	return cfg
+}
+
+// scopes returns required scopes of the route
+func (config IntrospectionConfig) scopes(ctx echo.Context) []string {
+	method := ctx.Request().Method
+	for _, route := range []string{method + " " + ctx.Path(), ctx.Path()} {
+		if scopes, ok := config.Routes[route]; ok {
+			return scopes
+		}
+	}
+
+	return config.Scopes
+}
+
+// IntrospectionMiddleware authenticates requests by opaque OAuth2 tokens checked by
+// RFC 7662 introspection endpoint.
+//
+// Active tokens are cached until expiration, so introspection endpoint is called once per token.
+// Concurrent requests with the same token share one introspection request.
+//
+// Token subject & scopes are available by [TokenSubject] & [TokenScopes] functions,
+// caller is available by [CurrentPrincipal] function.
+//
+// Missing or inactive tokens get 401 Unauthorized, tokens without required scopes get 403 Forbidden.
+// Both responses have "WWW-Authenticate" header
+func IntrospectionMiddleware(cfg IntrospectionConfig) echo.MiddlewareFunc {
+	config := newIntrospectionConfig(cfg)
+	introspector := &tokenIntrospector{
+		config: config,
+		cache:  make(map[string]introspectionCacheEntry),
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			token := bearerToken(ctx, config.Query, config.Cookie)
+			if token == "" {
+				return authFailure(ctx, config.Realm, "", ErrTokenMissing)
+			}
+
+			result, err := introspector.introspect(Context(ctx), token)
+			if err != nil {
+				return Error(ctx, err)
+			}
+
+			if !result.Active {
+				return authFailure(ctx, config.Realm, "invalid_token",
+					newTokenError(ErrTokenInvalid, errors.New("token is not active")))
+			}
+
+			if !result.ExpiresAt.IsZero() && time.Now().After(result.ExpiresAt) {
+				return authFailure(ctx, config.Realm, "invalid_token",
+					newTokenError(ErrTokenExpired, errors.New("token is expired")))
+			}
+
+			if missing := missingScopes(result.Scopes, config.scopes(ctx)); len(missing) > 0 {
+				return authFailure(ctx, config.Realm, "insufficient_scope", newScopeError(missing))
+			}
+
+			Set(ctx, introspectionKey, result)
+			SetPrincipal(ctx, Principal{
+				Subject:    result.Subject,
+				Scopes:     result.Scopes,
+				Source:     PrincipalSourceIntrospection,
+				Attributes: result.Claims,
+			})
+			return next(ctx)
+		}
+	}
+}
+
+// TokenIntrospection returns introspection result of the request token checked by [IntrospectionMiddleware]
+func TokenIntrospection(ctx echo.Context) (IntrospectionResult, bool) {
+	result, ok := Context(ctx).Value(introspectionKey).(IntrospectionResult)
+	return result, ok
+}
+
+// TokenSubject returns subject of the request token checked by [IntrospectionMiddleware]
+func TokenSubject(ctx echo.Context) string {
+	result, _ := TokenIntrospection(ctx)
+	return result.Subject
+}
+
+// TokenScopes returns scopes of the request token checked by [IntrospectionMiddleware]
+func TokenScopes(ctx echo.Context) []string {
+	result, _ := TokenIntrospection(ctx)
+	return result.Scopes
+}
+
+// tokenIntrospector calls introspection endpoint and caches active tokens
+type tokenIntrospector struct {
+	config    IntrospectionConfig
+	cache     map[string]introspectionCacheEntry
+	lastSweep time.Time
+	group     singleflight.Group
+	mx        sync.Mutex
+}
+
+type introspectionCacheEntry struct {
+	result    IntrospectionResult
+	expiresAt time.Time
+}
+
+func (introspector *tokenIntrospector) introspect(ctx context.Context, token string) (IntrospectionResult, error) {
+	// tokens are kept in memory by hashes only
+	hash := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(hash[:])
+
+	if result, ok := introspector.cached(key); ok {
+		return result, nil
+	}
+
+	// concurrent requests with the same token share one introspection
+	// and stop waiting for it if their context is canceled
+	flight := introspector.group.DoChan(key, func() (any, error) {
+		result, err := introspector.request(ctx, token)
+		if err != nil {
+			return IntrospectionResult{}, err
+		}
+
+		introspector.store(key, result)
+		return result, nil
+	})
+
+	select {
+	case introspected := <-flight:
+		if introspected.Err != nil {
+			return IntrospectionResult{}, ErrIntrospection.SetError(introspected.Err)
+		}
+
+		return introspected.Val.(IntrospectionResult), nil
+	case <-ctx.Done():
+		return IntrospectionResult{}, ErrIntrospection.SetError(ctx.Err())
+	}
+}
+
+func (introspector *tokenIntrospector) cached(key string) (IntrospectionResult, bool) {
+	introspector.mx.Lock()
+	defer introspector.mx.Unlock()
+
+	entry, ok := introspector.cache[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return IntrospectionResult{}, false
+	}
+
+	return entry.result, true
+}
+
+func (introspector *tokenIntrospector) store(key string, result IntrospectionResult) {
+	if !result.Active || introspector.config.MaxCacheTTL < 0 {
+		return
+	}
+
+	now := time.Now()
+	ttl := defaultIntrospectionCacheTTL
+	if !result.ExpiresAt.IsZero() {
+		ttl = result.ExpiresAt.Sub(now)
+	}
+
+	if maxTTL := introspector.config.MaxCacheTTL; maxTTL > 0 && ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	introspector.mx.Lock()
+	defer introspector.mx.Unlock()
+
+	introspector.sweep(now)
+	introspector.cache[key] = introspectionCacheEntry{
+		result:    result,
+		expiresAt: now.Add(ttl),
+	}
+}
+
+// sweep removes expired tokens not often than once a minute
+func (introspector *tokenIntrospector) sweep(now time.Time) {
+	if now.Sub(introspector.lastSweep) < time.Minute {
+		return
+	}
+
+	introspector.lastSweep = now
+	for key, entry := range introspector.cache {
+		if now.After(entry.expiresAt) {
+			delete(introspector.cache, key)
+		}
+	}
+}
+
+func (introspector *tokenIntrospector) request(ctx context.Context, token string) (IntrospectionResult, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+
+	// introspection must not be canceled by one of requests which share it,
+	// but it is limited by timeout even if client has no timeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultIntrospectionTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		introspector.config.URL,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return IntrospectionResult{}, err
+	}
+
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	request.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	if config := introspector.config; config.ClientID != "" {
+		// RFC 6749 requires form encoding of client credentials
+		request.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+
+	response, err := introspector.config.Client.Do(request)
+	if err != nil {
+		return IntrospectionResult{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return IntrospectionResult{}, errors.New("introspection endpoint returned " + response.Status)
+	}
+
+	blob, err := io.ReadAll(io.LimitReader(response.Body, maxIntrospectionSize))
+	if err != nil {
+		return IntrospectionResult{}, err
+	}
+
+	return parseIntrospection(blob)
+}
+
+func parseIntrospection(blob []byte) (IntrospectionResult, error) {
+	claims := make(map[string]any)
+	if err := json.Unmarshal(blob, &claims); err != nil {
+		return IntrospectionResult{}, err
+	}
+
+	text := func(name string) string {
+		value, _ := claims[name].(string)
+		return value
+	}
+
+	active, _ := claims["active"].(bool)
+	result := IntrospectionResult{
+		Active:    active,
+		Subject:   text("sub"),
+		Scopes:    tokenScopes(claims),
+		ClientID:  text("client_id"),
+		Username:  text("username"),
+		TokenType: text("token_type"),
+		Issuer:    text("iss"),
+		Claims:    claims,
+	}
+
+	switch audience := claims["aud"].(type) {
+	case string:
+		result.Audience = []string{audience}
+	case []any:
+		for _, value := range audience {
+			if text, ok := value.(string); ok {
+				result.Audience = append(result.Audience, text)
+			}
+		}
+	}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		result.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package echox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// introspectionServer is local RFC 7662 endpoint which responds by token
+type introspectionServer struct {
+	*httptest.Server
+	calls atomic.Int32
+}
+
+func newIntrospectionServer(t *testing.T, tokens map[string]map[string]any) *introspectionServer {
+	t.Helper()
+
+	server := &introspectionServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.calls.Add(1)
+
+		if id, secret, ok := r.BasicAuth(); !ok || id != "api" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		token := r.PostFormValue("token")
+		if token == "failure" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		response, ok := tokens[token]
+		if !ok {
+			response = map[string]any{"active": false}
+		}
+
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func serveIntrospection(config IntrospectionConfig, method, token string) *httptest.ResponseRecorder {
+	handler := echo.New()
+	handler.Any("/orders", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, TokenSubject(ctx))
+	}, IntrospectionMiddleware(config))
+
+	request := httptest.NewRequest(method, "/orders", nil)
+	request.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIntrospectionMiddleware(t *testing.T) {
+	server := newIntrospectionServer(t, map[string]map[string]any{
+		"reader": {"active": true, "sub": "alice", "scope": "orders:read"},
+		"writer": {"active": true, "sub": "bob", "scope": "orders:read orders:write"},
+	})
+
+	config := IntrospectionConfig{
+		URL:          server.URL,
+		ClientID:     "api",
+		ClientSecret: "secret",
+		Scopes:       []string{"orders:read"},
+		Routes:       map[string][]string{"POST /orders": {"orders:write"}},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		status int
+		body   string
+	}{
+		{"active token", http.MethodGet, "reader", http.StatusOK, "alice"},
+		{"inactive token", http.MethodGet, "unknown", http.StatusUnauthorized, ""},
+		{"route scope missing", http.MethodPost, "reader", http.StatusForbidden, ""},
+		{"route scope granted", http.MethodPost, "writer", http.StatusOK, "bob"},
+		{"endpoint failure", http.MethodGet, "failure", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serveIntrospection(config, tt.method, tt.token)
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+
+			if tt.body != "" && recorder.Body.String() != tt.body {
+				t.Fatalf("body = %q, want %q", recorder.Body.String(), tt.body)
+			}
+
+			if tt.status == http.StatusUnauthorized && recorder.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+				t.Fatal("WWW-Authenticate header is not set")
+			}
+		})
+	}
+}
+
+func TestIntrospectionMiddlewareCache(t *testing.T) {
+	expiresAt := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	server := newIntrospectionServer(t, map[string]map[string]any{
+		"token": {"active": true, "sub": "alice", "exp": expiresAt.Unix()},
+	})
+
+	handler := echo.New()
+	handler.GET("/orders", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}, IntrospectionMiddleware(IntrospectionConfig{URL: server.URL, ClientID: "api", ClientSecret: "secret"}))
+
+	serve := func() int {
+		request := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		request.Header.Set(echo.HeaderAuthorization, "Bearer token")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	for range 3 {
+		if status := serve(); status != http.StatusOK {
+			t.Fatalf("status = %d, want %d", status, http.StatusOK)
+		}
+	}
+
+	if calls := server.calls.Load(); calls != 1 {
+		t.Fatalf("introspection calls = %d, want 1", calls)
+	}
+
+	// token is cached till expiration only
+	time.Sleep(time.Until(expiresAt) + 100*time.Millisecond)
+	if status := serve(); status != http.StatusUnauthorized {
+		t.Fatalf("status after expiration = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	if calls := server.calls.Load(); calls != 2 {
+		t.Fatalf("introspection calls = %d, want 2", calls)
+	}
+}
+
+func TestIntrospectionCanceledFollower(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]any{"active": true, "sub": "alice"})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	introspector := &tokenIntrospector{
+		config: newIntrospectionConfig(IntrospectionConfig{URL: server.URL}),
+		cache:  make(map[string]introspectionCacheEntry),
+	}
+
+	go func() {
+		_, _ = introspector.introspect(context.Background(), "token")
+	}()
+	<-started
+
+	// follower waits for the shared introspection till its context is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := introspector.introspect(ctx, "token")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !isError(err, ErrIntrospection) {
+			t.Fatalf("error = %v, want %v", err, ErrIntrospection)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("canceled follower waits for the leader")
+	}
+}
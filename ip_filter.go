@@ -0,0 +1,241 @@
+package echox
+
+import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultIPFilterReloadInterval = 5 * time.Second
+
+// IPFilterConfig describes [IPFilter]. Networks are CIDRs or single IPv4/IPv6 addresses.
+type IPFilterConfig struct {
+	// Allow are networks allowed to access. If Allow list (with file) is empty, all addresses are denied
+	// unless DefaultAllow is set
+	Allow []string
+	// Deny are networks denied to access. Deny takes precedence over Allow
+	Deny []string
+	// DefaultAllow allows addresses not in Deny when Allow list is empty, so filter works as deny list only.
+	// Disabled by default, so empty or truncated file does not open access to everyone
+	DefaultAllow bool
+	// File is JSON file with lists added to Allow & Deny: {"allow": ["10.8.0.0/16"], "deny": ["10.8.3.0/24"]}.
+	// File is reloaded when it is changed. File with unknown fields is invalid
+	File string
+	// ReloadInterval is how often file is checked for changes. Default is 5 seconds
+	ReloadInterval time.Duration
+}
+
+// ipFilterFile is content of [IPFilterConfig] file
+type ipFilterFile struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// IPFilter allows or denies requests by client IP address (see [ClientIP]).
+type IPFilter struct {
+	config    IPFilterConfig
+	allow     []netip.Prefix
+	deny      []netip.Prefix
+	modTime   time.Time
+	checkedAt time.Time
+	mx        sync.RWMutex
+}
+
+// NewIPFilter creates [IPFilter] by provided lists and file. Returns ErrIPFilter if any network is invalid
+// or file could not be read
+func NewIPFilter(config IPFilterConfig) (*IPFilter, error) {
+	if config.ReloadInterval <= 0 {
+		config.ReloadInterval = defaultIPFilterReloadInterval
+	}
+
+	filter := &IPFilter{
+		config: config,
+	}
+
+	if err := filter.load(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// Allowed checks if provided IP address is allowed. Invalid addresses are never allowed
+func (filter *IPFilter) Allowed(ip string) bool {
+	allowed, _ := filter.check(ip)
+	return allowed
+}
+
+// check returns if address is allowed and the reason if it is not
+func (filter *IPFilter) check(ip string) (bool, string) {
+	filter.reload()
+
+	address, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, "invalid_ip"
+	}
+	address = address.Unmap()
+
+	filter.mx.RLock()
+	defer filter.mx.RUnlock()
+
+	if prefixesContain(filter.deny, address) {
+		return false, "deny_list"
+	}
+
+	if len(filter.allow) == 0 && filter.config.DefaultAllow {
+		return true, ""
+	}
+
+	if !prefixesContain(filter.allow, address) {
+		return false, "not_allowed"
+	}
+
+	return true, ""
+}
+
+// Reload loads lists from file. Filter keeps previous lists if file is invalid
+func (filter *IPFilter) Reload() error {
+	return filter.load()
+}
+
+// reload loads lists if file was changed. File is checked not often than once per reload interval.
+// Errors are logged, so broken file does not open or close access
+func (filter *IPFilter) reload() {
+	if filter.config.File == "" {
+		return
+	}
+
+	filter.mx.Lock()
+	if time.Since(filter.checkedAt) < filter.config.ReloadInterval {
+		filter.mx.Unlock()
+		return
+	}
+	filter.checkedAt = time.Now()
+	modTime := filter.modTime
+	filter.mx.Unlock()
+
+	info, err := os.Stat(filter.config.File)
+	if err != nil {
+		log.Error().Err(err).Str("file", filter.config.File).Msg("Check IP filter file")
+		return
+	}
+
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+
+	if err = filter.load(); err != nil {
+		log.Error().Err(err).Str("file", filter.config.File).Msg("Reload IP filter file")
+	}
+}
+
+func (filter *IPFilter) load() error {
+	lists := ipFilterFile{
+		Allow: filter.config.Allow,
+		Deny:  filter.config.Deny,
+	}
+
+	var modTime time.Time
+	if filter.config.File != "" {
+		file, err := os.Open(filter.config.File)
+		if err != nil {
+			return ErrIPFilter.SetError(err)
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return ErrIPFilter.SetError(err)
+		}
+
+		var content ipFilterFile
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&content); err != nil {
+			return ErrIPFilter.SetError(err)
+		}
+
+		modTime = info.ModTime()
+		lists.Allow = append(append(make([]string, 0), lists.Allow...), content.Allow...)
+		lists.Deny = append(append(make([]string, 0), lists.Deny...), content.Deny...)
+	}
+
+	allow, err := parsePrefixes(lists.Allow)
+	if err != nil {
+		return err
+	}
+
+	deny, err := parsePrefixes(lists.Deny)
+	if err != nil {
+		return err
+	}
+
+	filter.mx.Lock()
+	defer filter.mx.Unlock()
+
+	filter.allow = allow
+	filter.deny = deny
+	filter.modTime = modTime
+	filter.checkedAt = time.Now()
+	return nil
+}
+
+func parsePrefixes(networks []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		prefix, err := parsePrefix(network)
+		if err != nil {
+			return nil, ErrIPFilter.SetError(err)
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+func prefixesContain(prefixes []netip.Prefix, address netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IPFilterMiddleware allows requests only from addresses allowed by provided filter.
+// Could be used globally, per group or per route:
+//
+//	filter, err := echox.NewIPFilter(echox.IPFilterConfig{Allow: []string{"10.8.0.0/16"}})
+//	admin := echox.Group("/admin", echox.IPFilterMiddleware(filter))
+//
+// Denied requests get 403 Forbidden and are logged
+func IPFilterMiddleware(filter *IPFilter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ip := ClientIP(ctx)
+			allowed, reason := filter.check(ip)
+			if allowed {
+				return next(ctx)
+			}
+
+			log.Warn().
+				Ctx(Context(ctx)).
+				Str("ip", ip).
+				Str("remote_addr", ctx.Request().RemoteAddr).
+				Str("method", ctx.Request().Method).
+				Str("path", ctx.Request().URL.Path).
+				Str("reason", reason).
+				Str("user_agent", ctx.Request().UserAgent()).
+				Msg("IP address denied")
+
+			return Error(ctx, ErrIPDenied)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package echox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeIPFilterFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ip_filter.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  IPFilterConfig
+		file    string
+		allowed map[string]bool
+	}{
+		{
+			name:    "allow list",
+			config:  IPFilterConfig{Allow: []string{"10.8.0.0/16"}, Deny: []string{"10.8.3.0/24"}},
+			allowed: map[string]bool{"10.8.1.1": true, "10.8.3.1": false, "203.0.113.9": false, "invalid": false},
+		},
+		{
+			name:    "empty allow list",
+			config:  IPFilterConfig{Deny: []string{"203.0.113.0/24"}},
+			allowed: map[string]bool{"10.8.1.1": false, "203.0.113.9": false},
+		},
+		{
+			name:    "deny list only",
+			config:  IPFilterConfig{Deny: []string{"203.0.113.0/24"}, DefaultAllow: true},
+			allowed: map[string]bool{"10.8.1.1": true, "203.0.113.9": false},
+		},
+		{
+			name:    "file",
+			file:    `{"allow": ["10.8.0.0/16", "::ffff:192.0.2.1"], "deny": ["10.8.3.0/24"]}`,
+			allowed: map[string]bool{"10.8.1.1": true, "10.8.3.1": false, "192.0.2.1": true},
+		},
+		{
+			name:    "empty file",
+			file:    `{}`,
+			allowed: map[string]bool{"10.8.1.1": false, "203.0.113.9": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				tt.config.File = writeIPFilterFile(t, tt.file)
+			}
+
+			filter, err := NewIPFilter(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for ip, allowed := range tt.allowed {
+				if filter.Allowed(ip) != allowed {
+					t.Errorf("%s: expected allowed %t", ip, allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestIPFilterInvalidFile(t *testing.T) {
+	for _, content := range []string{
+		`{"allowed": ["10.8.0.0/16"]}`,
+		`{"allow": ["10.8.0.0/33"]}`,
+		`{"allow": [`,
+	} {
+		_, err := NewIPFilter(IPFilterConfig{File: writeIPFilterFile(t, content)})
+		if !isError(err, ErrIPFilter) {
+			t.Errorf("%s: expected IP filter error, got %v", content, err)
+		}
+	}
+}
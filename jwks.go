@@ -0,0 +1,327 @@
+package echox
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultJWKSRefreshInterval    = time.Hour
+	defaultJWKSMinRefreshInterval = time.Minute
+	defaultJWKSTimeout            = 10 * time.Second
+	maxJWKSSize                   = 1 << 20 // 1MB
+)
+
+// JWKSConfig describes [JWKS] source.
+type JWKSConfig struct {
+	// URL of JWKS endpoint. URL or File must be provided
+	URL string
+	// File is path to local JWKS file
+	File string
+	// RefreshInterval is how often keys are reloaded. Default is 1 hour
+	RefreshInterval time.Duration
+	// MinRefreshInterval limits reloads caused by tokens with unknown key ID. Default is 1 minute
+	MinRefreshInterval time.Duration
+	// Client is HTTP client for URL loading. Default is client with 10 seconds timeout
+	Client *http.Client
+}
+
+// JWKS is JSON Web Key Set loaded from URL or file. Keys are cached and reloaded periodically
+// or when token is signed by unknown key (key rotation).
+type JWKS struct {
+	config   JWKSConfig
+	keys     map[string]jwk
+	loadedAt time.Time
+	triedAt  time.Time
+	flight   singleflight.Group
+	mx       sync.RWMutex
+}
+
+// jwk is parsed JSON Web Key
+type jwk struct {
+	key any
+	alg string
+}
+
+type jwkJSON struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// NewJWKS creates [JWKS] and loads keys
+func NewJWKS(ctx context.Context, cfg JWKSConfig) (*JWKS, error) {
+	if cfg.URL == "" && cfg.File == "" {
+		return nil, ErrJWKS.SetError(errors.New("JWKS URL or file must be provided"))
+	}
+
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultJWKSRefreshInterval
+	}
+
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = defaultJWKSMinRefreshInterval
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultJWKSTimeout}
+	}
+
+	set := &JWKS{
+		config: cfg,
+		keys:   make(map[string]jwk),
+	}
+
+	if err := set.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// Key returns key by key ID and its algorithm (if set in JWKS).
+// Reloads keys if they are outdated or key ID is unknown
+func (set *JWKS) Key(ctx context.Context, kid string) (any, string, error) {
+	set.mx.RLock()
+	key, ok := set.keys[kid]
+	loadedAt, triedAt := set.loadedAt, set.triedAt
+	set.mx.RUnlock()
+
+	fresh := ok && time.Since(loadedAt) < set.config.RefreshInterval
+	if fresh || time.Since(triedAt) < set.config.MinRefreshInterval {
+		if !ok {
+			return nil, "", ErrJWKSKeyNotFound
+		}
+
+		return key.key, key.alg, nil
+	}
+
+	if err := set.refreshAfter(ctx, triedAt); err != nil {
+		// outdated keys are still better than nothing
+		if ok {
+			return key.key, key.alg, nil
+		}
+
+		return nil, "", err
+	}
+
+	set.mx.RLock()
+	defer set.mx.RUnlock()
+
+	key, ok = set.keys[kid]
+	if !ok {
+		return nil, "", ErrJWKSKeyNotFound
+	}
+
+	return key.key, key.alg, nil
+}
+
+// Refresh reloads keys
+func (set *JWKS) Refresh(ctx context.Context) error {
+	return set.refreshAfter(ctx, time.Time{})
+}
+
+// refreshAfter reloads keys if there was no reload attempt after provided time (by concurrent request).
+// Concurrent requests share one reload and stop waiting for it if their context is canceled
+func (set *JWKS) refreshAfter(ctx context.Context, triedAt time.Time) error {
+	result := set.flight.DoChan("refresh", func() (any, error) {
+		set.mx.RLock()
+		reloaded := !triedAt.IsZero() && set.triedAt.After(triedAt)
+		set.mx.RUnlock()
+
+		if reloaded {
+			return nil, nil
+		}
+
+		// reload is shared, so it is not canceled with the request started it
+		reloadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultJWKSTimeout)
+		defer cancel()
+
+		return nil, set.reload(reloadCtx)
+	})
+
+	select {
+	case loaded := <-result:
+		return loaded.Err
+	case <-ctx.Done():
+		return ErrJWKS.SetError(ctx.Err())
+	}
+}
+
+// reload loads keys. Attempt time is set after loading, so requests coming while keys are loaded
+// join the reload instead of getting outdated keys
+func (set *JWKS) reload(ctx context.Context) error {
+	keys, err := set.fetch(ctx)
+
+	set.mx.Lock()
+	defer set.mx.Unlock()
+
+	set.triedAt = time.Now()
+	if err != nil {
+		return err
+	}
+
+	set.keys = keys
+	set.loadedAt = set.triedAt
+	return nil
+}
+
+func (set *JWKS) fetch(ctx context.Context) (map[string]jwk, error) {
+	blob, err := set.load(ctx)
+	if err != nil {
+		return nil, ErrJWKS.SetError(err)
+	}
+
+	keys, err := parseJWKS(blob)
+	if err != nil {
+		return nil, ErrJWKS.SetError(err)
+	}
+
+	return keys, nil
+}
+
+func (set *JWKS) load(ctx context.Context) ([]byte, error) {
+	if set.config.File != "" {
+		return os.ReadFile(set.config.File)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, set.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := set.config.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("JWKS endpoint returned " + response.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, maxJWKSSize))
+}
+
+func parseJWKS(blob []byte) (map[string]jwk, error) {
+	var set struct {
+		Keys []jwkJSON `json:"keys"`
+	}
+	if err := json.Unmarshal(blob, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]jwk, len(set.Keys))
+	for _, raw := range set.Keys {
+		// skip encryption keys
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+
+		key, err := raw.parse()
+		if err != nil {
+			return nil, errors.New("JWK " + raw.Kid + ": " + err.Error())
+		}
+
+		keys[raw.Kid] = jwk{
+			key: key,
+			alg: raw.Alg,
+		}
+	}
+
+	return keys, nil
+}
+
+func (raw jwkJSON) parse() (any, error) {
+	switch raw.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(raw.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeJWKInt(raw.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		return raw.parseEC()
+	case "OKP":
+		if raw.Crv != "Ed25519" {
+			return nil, errors.New("unsupported OKP curve " + raw.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(raw.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(raw.K)
+	default:
+		return nil, errors.New("unsupported key type " + raw.Kty)
+	}
+}
+
+func (raw jwkJSON) parseEC() (any, error) {
+	var curve elliptic.Curve
+	switch raw.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, errors.New("unsupported EC curve " + raw.Crv)
+	}
+
+	x, err := decodeJWKInt(raw.X)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := decodeJWKInt(raw.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	if !curve.IsOnCurve(x, y) { //nolint:staticcheck
+		return nil, errors.New("EC point is not on curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeJWKInt(value string) (*big.Int, error) {
+	blob, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(blob), nil
+}
@@ -0,0 +1,281 @@
+package echox
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	jwtClaimsKey = "jwt-claims"
+
+	bearerScheme = "Bearer"
+)
+
+var (
+	jwtHMACAlgorithms   = []string{"HS256", "HS384", "HS512"}
+	jwtRSAAlgorithms    = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	jwtECDSAAlgorithms  = []string{"ES256", "ES384", "ES512"}
+	jwtEdDSAAlgorithms  = []string{"EdDSA"}
+	jwtKeySetAlgorithms = slices.Concat(jwtRSAAlgorithms, jwtECDSAAlgorithms, jwtEdDSAAlgorithms)
+)
+
+// JWTConfig describes [JWTMiddleware].
+type JWTConfig struct {
+	// Key verifies tokens signature: []byte secret (HS*), *rsa.PublicKey (RS*, PS*),
+	// *ecdsa.PublicKey (ES*) or ed25519.PublicKey (EdDSA). Key or KeySet must be provided
+	Key any
+	// KeySet verifies tokens by "kid" header
+	KeySet *JWKS
+	// Algorithms are allowed signing algorithms. Default are algorithms of the key type
+	Algorithms []string
+	// Audience is list of accepted audiences. If set, token must have one of them
+	Audience []string
+	// Issuer is expected token issuer
+	Issuer string
+	// Leeway is allowed clock skew for "exp", "nbf" & "iat" checks
+	Leeway time.Duration
+	// Scopes are required token scopes (from "scope" or "scp" claims). Missing scopes cause 403
+	Scopes []string
+	// Query is query param with token (if token is not in "Authorization" header)
+	Query string
+	// Cookie is cookie with token (if token is not in "Authorization" header)
+	Cookie string
+	// Realm is "WWW-Authenticate" header realm
+	Realm string
+}
+
+func (config JWTConfig) algorithms() []string {
+	if len(config.Algorithms) > 0 {
+		return config.Algorithms
+	}
+
+	switch config.Key.(type) {
+	case []byte:
+		return jwtHMACAlgorithms
+	case *rsa.PublicKey:
+		return jwtRSAAlgorithms
+	case *ecdsa.PublicKey:
+		return jwtECDSAAlgorithms
+	case ed25519.PublicKey:
+		return jwtEdDSAAlgorithms
+	default:
+		return jwtKeySetAlgorithms
+	}
+}
+
+// JWTMiddleware authenticates requests by JWT bearer tokens.
+//
+// Token is taken from "Authorization: Bearer" header (or from configured query param or cookie),
+// its signature, expiration, audience & issuer are checked. Claims are available by [Claims] function,
+// caller is available by [CurrentPrincipal] function.
+//
+// Missing or invalid tokens get 401 Unauthorized, tokens without required scopes get 403 Forbidden.
+// Both responses have "WWW-Authenticate" header
+func JWTMiddleware(cfg JWTConfig) echo.MiddlewareFunc {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(cfg.algorithms()),
+		jwt.WithLeeway(cfg.Leeway),
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			raw := bearerToken(ctx, cfg.Query, cfg.Cookie)
+			if raw == "" {
+				return authFailure(ctx, cfg.Realm, "", ErrTokenMissing)
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := parser.ParseWithClaims(raw, claims, cfg.keyFunc(ctx)); err != nil {
+				switch {
+				case isError(err, ErrJWKS):
+					// key set could not be loaded, it is not client failure
+					return Error(ctx, err)
+				case errors.Is(err, jwt.ErrTokenExpired):
+					return authFailure(ctx, cfg.Realm, "invalid_token", newTokenError(ErrTokenExpired, err))
+				default:
+					return authFailure(ctx, cfg.Realm, "invalid_token", newTokenError(ErrTokenInvalid, err))
+				}
+			}
+
+			if err := cfg.validate(claims); err != nil {
+				return authFailure(ctx, cfg.Realm, "invalid_token", err)
+			}
+
+			scopes := tokenScopes(claims)
+			if missing := missingScopes(scopes, cfg.Scopes); len(missing) > 0 {
+				return authFailure(ctx, cfg.Realm, "insufficient_scope", newScopeError(missing))
+			}
+
+			Set(ctx, jwtClaimsKey, claims)
+			SetPrincipal(ctx, jwtPrincipal(claims, scopes))
+			return next(ctx)
+		}
+	}
+}
+
+func (config JWTConfig) keyFunc(ctx echo.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		if config.KeySet == nil {
+			return config.Key, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, algorithm, err := config.KeySet.Key(Context(ctx), kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if algorithm != "" && algorithm != token.Method.Alg() {
+			return nil, errors.New("token algorithm does not match key algorithm")
+		}
+
+		return key, nil
+	}
+}
+
+// validate checks audience (any of configured) & issuer
+func (config JWTConfig) validate(claims jwt.MapClaims) error {
+	if config.Issuer != "" {
+		if issuer, _ := claims.GetIssuer(); issuer != config.Issuer {
+			return newTokenError(ErrTokenInvalid, jwt.ErrTokenInvalidIssuer)
+		}
+	}
+
+	if len(config.Audience) > 0 {
+		audience, _ := claims.GetAudience()
+		if !slices.ContainsFunc(audience, func(value string) bool {
+			return slices.Contains(config.Audience, value)
+		}) {
+			return newTokenError(ErrTokenInvalid, jwt.ErrTokenInvalidAudience)
+		}
+	}
+
+	return nil
+}
+
+// Claims returns claims of the token verified by [JWTMiddleware] converted to provided type
+// (structure with json tags or map)
+func Claims[T any](ctx echo.Context) (T, error) {
+	var claims T
+
+	raw, ok := Context(ctx).Value(jwtClaimsKey).(jwt.MapClaims)
+	if !ok {
+		return claims, ErrTokenMissing
+	}
+
+	blob, err := json.Marshal(raw)
+	if err != nil {
+		return claims, newTokenError(ErrTokenInvalid, err)
+	}
+
+	if err = json.Unmarshal(blob, &claims); err != nil {
+		return claims, newTokenError(ErrTokenInvalid, err)
+	}
+
+	return claims, nil
+}
+
+// jwtPrincipal creates [Principal] by "sub" & "roles" claims
+func jwtPrincipal(claims jwt.MapClaims, scopes []string) Principal {
+	subject, _ := claims.GetSubject()
+
+	roles := make([]string, 0)
+	switch value := claims["roles"].(type) {
+	case string:
+		roles = append(roles, value)
+	case []any:
+		for _, role := range value {
+			if text, ok := role.(string); ok {
+				roles = append(roles, text)
+			}
+		}
+	}
+
+	return Principal{
+		Subject:    subject,
+		Scopes:     scopes,
+		Roles:      roles,
+		Source:     PrincipalSourceJWT,
+		Attributes: claims,
+	}
+}
+
+// bearerToken returns token from "Authorization" header, query param or cookie
+func bearerToken(ctx echo.Context, query, cookie string) string {
+	authorization := ctx.Request().Header.Get(echo.HeaderAuthorization)
+	if scheme, token, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, bearerScheme) {
+		return strings.TrimSpace(token)
+	}
+
+	if query != "" {
+		if token := ctx.QueryParam(query); token != "" {
+			return token
+		}
+	}
+
+	if cookie != "" {
+		if value, err := ctx.Cookie(cookie); err == nil {
+			return value.Value
+		}
+	}
+
+	return ""
+}
+
+// tokenScopes returns scopes from "scope" (space separated string) or "scp" (string or list) claims
+func tokenScopes(claims map[string]any) []string {
+	scopes := make([]string, 0)
+	for _, name := range []string{"scope", "scp"} {
+		switch value := claims[name].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(value)...)
+		case []any:
+			for _, scope := range value {
+				if text, ok := scope.(string); ok {
+					scopes = append(scopes, text)
+				}
+			}
+		}
+	}
+
+	return scopes
+}
+
+// missingScopes returns required scopes which are not granted
+func missingScopes(granted, required []string) []string {
+	missing := make([]string, 0)
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
+
+// authFailure sets "WWW-Authenticate" header by RFC 6750 and returns failure
+func authFailure(ctx echo.Context, realm, code string, err error) error {
+	challenge := bearerScheme
+	params := make([]string, 0, 2)
+	if realm != "" {
+		params = append(params, `realm="`+realm+`"`)
+	}
+	if code != "" {
+		params = append(params, `error="`+code+`"`)
+	}
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+
+	ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge)
+	return Error(ctx, err)
+}
@@ -0,0 +1,202 @@
+package echox
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// jwksServer is local JWKS endpoint with replaceable keys
+type jwksServer struct {
+	*httptest.Server
+	keys  map[string]*rsa.PrivateKey
+	calls int
+	mx    sync.Mutex
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	t.Helper()
+
+	server := &jwksServer{}
+	server.rotate(t, kids...)
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		server.mx.Lock()
+		defer server.mx.Unlock()
+
+		server.calls++
+		keys := make([]map[string]string, 0, len(server.keys))
+		for kid, key := range server.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// rotate replaces served keys by new keys with provided IDs
+func (server *jwksServer) rotate(t *testing.T, kids ...string) {
+	t.Helper()
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	server.keys = make(map[string]*rsa.PrivateKey, len(kids))
+	for _, kid := range kids {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server.keys[kid] = key
+	}
+}
+
+func (server *jwksServer) token(t *testing.T, kid string, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	if key == nil {
+		server.mx.Lock()
+		key = server.keys[kid]
+		server.mx.Unlock()
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": "user",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func serveJWT(set *JWKS, token string) *httptest.ResponseRecorder {
+	handler := JWTMiddleware(JWTConfig{KeySet: set})(func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	_ = handler(echo.New().NewContext(request, recorder))
+	return recorder
+}
+
+func TestJWTMiddlewareKeyRotation(t *testing.T) {
+	server := newJWKSServer(t, "first")
+	set, err := NewJWKS(context.Background(), JWKSConfig{URL: server.URL, MinRefreshInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder := serveJWT(set, server.token(t, "first", nil)); recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	server.rotate(t, "second")
+	time.Sleep(5 * time.Millisecond)
+
+	if recorder := serveJWT(set, server.token(t, "second", nil)); recorder.Code != http.StatusOK {
+		t.Fatalf("status after rotation = %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestJWTMiddlewareUnknownKey(t *testing.T) {
+	server := newJWKSServer(t, "known")
+	set, err := NewJWKS(context.Background(), JWKSConfig{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := serveJWT(set, server.token(t, "unknown", key))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	if recorder.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+		t.Fatal("WWW-Authenticate header is not set")
+	}
+}
+
+func TestJWTMiddlewareUnreachableJWKS(t *testing.T) {
+	server := newJWKSServer(t, "first")
+	set, err := NewJWKS(context.Background(), JWKSConfig{URL: server.URL, MinRefreshInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.rotate(t, "second")
+	token := server.token(t, "second", nil)
+	server.Close()
+	time.Sleep(5 * time.Millisecond)
+
+	// key set could not be reloaded: it is server failure, not invalid token
+	if recorder := serveJWT(set, token); recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusInternalServerError, recorder.Body.String())
+	}
+
+	if _, err = NewJWKS(context.Background(), JWKSConfig{URL: server.URL}); !isError(err, ErrJWKS) {
+		t.Fatalf("error = %v, want %v", err, ErrJWKS)
+	}
+}
+
+func TestJWKSConcurrentRefresh(t *testing.T) {
+	server := newJWKSServer(t, "first")
+	set, err := NewJWKS(context.Background(), JWKSConfig{URL: server.URL, MinRefreshInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.rotate(t, "second")
+	time.Sleep(5 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := set.Key(context.Background(), "second"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	// initial load & at most a few shared reloads
+	if server.calls > 3 {
+		t.Fatalf("JWKS loaded %d times", server.calls)
+	}
+}
@@ -0,0 +1,473 @@
+package echox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/boostgo/contextx"
+	"github.com/boostgo/errorx"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultMultipartMaxFileSize  = 32 << 20 // 32MB
+	defaultMultipartMaxTotalSize = 64 << 20 // 64MB
+	defaultMultipartMaxFieldSize = 1 << 20  // 1MB
+	defaultMultipartMaxFiles     = 10
+	defaultMultipartMaxFields    = 100
+)
+
+// MultipartConfig describes limits of streaming multipart reading.
+//
+// All zero values are replaced by defaults
+type MultipartConfig struct {
+	// MaxFileSize is maximum size of one file in bytes. Default is 32MB
+	MaxFileSize int64
+	// MaxTotalSize is maximum size of whole request body in bytes. Default is 64MB
+	MaxTotalSize int64
+	// MaxFieldSize is maximum size of one non-file value in bytes. Default is 1MB
+	MaxFieldSize int64
+	// MaxFiles is maximum count of files. Default is 10
+	MaxFiles int
+	// MaxFields is maximum count of non-file values. Default is 100
+	MaxFields int
+	// AllowedFields is list of allowed field names (both for files and values). Empty list allows any field
+	AllowedFields []string
+}
+
+func newMultipartConfig(cfg ...MultipartConfig) MultipartConfig {
+	var config MultipartConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaultMultipartMaxFileSize
+	}
+
+	if config.MaxTotalSize <= 0 {
+		config.MaxTotalSize = defaultMultipartMaxTotalSize
+	}
+
+	if config.MaxFieldSize <= 0 {
+		config.MaxFieldSize = defaultMultipartMaxFieldSize
+	}
+
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = defaultMultipartMaxFiles
+	}
+
+	if config.MaxFields <= 0 {
+		config.MaxFields = defaultMultipartMaxFields
+	}
+
+	return config
+}
+
+// MultipartStream reads multipart form part by part without loading it to memory.
+type MultipartStream struct {
+	reader    *multipart.Reader
+	config    MultipartConfig
+	files     int
+	fields    int
+	violation error
+}
+
+// MultipartPart is one part (file or value) of multipart form.
+//
+// Part could be read only till the next [MultipartStream.Next] call
+type MultipartPart struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Header      textproto.MIMEHeader
+
+	reader io.Reader
+}
+
+// NewMultipartStream creates [MultipartStream] from request body.
+//
+// Request must have "multipart/form-data" content type
+func NewMultipartStream(ctx echo.Context, cfg ...MultipartConfig) (*MultipartStream, error) {
+	if err := contextx.Validate(Context(ctx)); err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(Header(ctx, echo.HeaderContentType).String())
+	if err != nil || mediaType != echo.MIMEMultipartForm || params["boundary"] == "" {
+		return nil, wrapError(ErrMultipartInvalid, errors.New("request is not multipart/form-data"))
+	}
+
+	stream := &MultipartStream{
+		config: newMultipartConfig(cfg...),
+	}
+
+	body := newSizeLimitReader(ctx.Request().Body, stream.config.MaxTotalSize, func() error {
+		return newMultipartLimitError(ErrMultipartTooLarge, "", stream.config.MaxTotalSize)
+	})
+	stream.reader = multipart.NewReader(body, params["boundary"])
+
+	return stream, nil
+}
+
+// Next returns next part of multipart form or [io.EOF] if there are no parts anymore.
+//
+// Returns typed errors if limits from [MultipartConfig] are violated
+func (stream *MultipartStream) Next() (*MultipartPart, error) {
+	if stream.violation != nil {
+		return nil, stream.violation
+	}
+
+	part, err := stream.reader.NextPart()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		return nil, stream.fail(err)
+	}
+
+	field := part.FormName()
+	if len(stream.config.AllowedFields) > 0 && !slices.Contains(stream.config.AllowedFields, field) {
+		return nil, stream.setViolation(newMultipartFieldNotAllowedError(field))
+	}
+
+	result := &MultipartPart{
+		Field:       field,
+		ContentType: part.Header.Get(echo.HeaderContentType),
+		Header:      part.Header,
+	}
+
+	if part.FileName() == "" {
+		stream.fields++
+		if stream.fields > stream.config.MaxFields {
+			limitErr := newMultipartLimitError(ErrMultipartTooManyFields, field, int64(stream.config.MaxFields))
+			return nil, stream.setViolation(limitErr)
+		}
+
+		result.reader = stream.limitPart(part, field, ErrMultipartFieldTooLarge, stream.config.MaxFieldSize)
+		return result, nil
+	}
+
+	stream.files++
+	if stream.files > stream.config.MaxFiles {
+		limitErr := newMultipartLimitError(ErrMultipartTooManyFiles, field, int64(stream.config.MaxFiles))
+		return nil, stream.setViolation(limitErr)
+	}
+
+	result.Filename = filepath.Base(part.FileName())
+	result.reader = stream.limitPart(part, field, ErrMultipartFileTooLarge, stream.config.MaxFileSize)
+	return result, nil
+}
+
+func (stream *MultipartStream) limitPart(part io.Reader, field string, limitErr *errorx.Error, limit int64) io.Reader {
+	reader := newSizeLimitReader(part, limit, func() error {
+		return newMultipartLimitError(limitErr, field, limit)
+	})
+
+	return &violationReader{
+		reader: reader,
+		stream: stream,
+	}
+}
+
+func (stream *MultipartStream) setViolation(err error) error {
+	stream.violation = err
+	return err
+}
+
+// fail converts reading error to typed one, keeping limit violations as is
+func (stream *MultipartStream) fail(err error) error {
+	var limitErr *sizeLimitError
+	if errors.As(err, &limitErr) {
+		return stream.setViolation(limitErr.err)
+	}
+
+	return wrapError(ErrMultipartInvalid, err)
+}
+
+// IsFile returns true if part is uploaded file
+func (part *MultipartPart) IsFile() bool {
+	return part.Filename != ""
+}
+
+// Read reads part content
+func (part *MultipartPart) Read(p []byte) (int, error) {
+	return part.reader.Read(p)
+}
+
+// Value reads whole part content as string. Use it for non-file parts
+func (part *MultipartPart) Value() (string, error) {
+	value, err := io.ReadAll(part.reader)
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
+
+// UploadedFile is file which was saved by [UploadSink].
+type UploadedFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Location is where sink saved the file (file path, storage key, etc.)
+	Location string `json:"location"`
+}
+
+// UploadSink saves uploaded file content. Sink may set [UploadedFile.Location].
+type UploadSink interface {
+	Save(ctx context.Context, file *UploadedFile, content io.Reader) error
+}
+
+// UploadRemover is optional [UploadSink] interface which allows to remove already saved files
+// if multipart form reading failed.
+type UploadRemover interface {
+	Remove(ctx context.Context, file UploadedFile) error
+}
+
+// UploadSinkFunc is function implementation of [UploadSink].
+type UploadSinkFunc func(ctx context.Context, file *UploadedFile, content io.Reader) error
+
+func (fn UploadSinkFunc) Save(ctx context.Context, file *UploadedFile, content io.Reader) error {
+	return fn(ctx, file, content)
+}
+
+// MultipartResult is result of [StreamMultipart]: all values & saved files.
+type MultipartResult struct {
+	Values map[string][]string
+	Files  []UploadedFile
+}
+
+// StreamMultipart reads multipart form by [MultipartStream], collects all values and saves all files by provided sink.
+//
+// If reading failed and sink implements [UploadRemover], all saved files will be removed
+func StreamMultipart(ctx echo.Context, sink UploadSink, cfg ...MultipartConfig) (*MultipartResult, error) {
+	stream, err := NewMultipartStream(ctx, cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MultipartResult{
+		Values: make(map[string][]string),
+		Files:  make([]UploadedFile, 0),
+	}
+
+	if err = streamMultipart(ctx, stream, sink, result); err != nil {
+		if remover, ok := sink.(UploadRemover); ok {
+			for _, file := range result.Files {
+				_ = remover.Remove(Context(ctx), file)
+			}
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func streamMultipart(ctx echo.Context, stream *MultipartStream, sink UploadSink, result *MultipartResult) error {
+	for {
+		part, err := stream.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		if !part.IsFile() {
+			value, err := part.Value()
+			if err != nil {
+				return err
+			}
+
+			result.Values[part.Field] = append(result.Values[part.Field], value)
+			continue
+		}
+
+		file := UploadedFile{
+			Field:       part.Field,
+			Filename:    part.Filename,
+			ContentType: part.ContentType,
+		}
+
+		counter := &countingReader{reader: part}
+		if err = sink.Save(Context(ctx), &file, counter); err != nil {
+			if stream.violation != nil {
+				return stream.violation
+			}
+
+			return ErrMultipartSave.SetError(err)
+		}
+
+		file.Size = counter.count
+		result.Files = append(result.Files, file)
+	}
+}
+
+type tempDirSink struct {
+	dir string
+}
+
+// NewTempDirSink creates [UploadSink] which saves files to provided directory (or [os.TempDir] if empty).
+//
+// [UploadedFile.Location] will be path to the saved file
+func NewTempDirSink(dir string) UploadSink {
+	return &tempDirSink{
+		dir: dir,
+	}
+}
+
+func (sink *tempDirSink) Save(_ context.Context, file *UploadedFile, content io.Reader) error {
+	target, err := os.CreateTemp(sink.dir, "echox-upload-*"+filepath.Ext(file.Filename))
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(target, content); err != nil {
+		_ = target.Close()
+		_ = os.Remove(target.Name())
+		return err
+	}
+
+	file.Location = target.Name()
+	return target.Close()
+}
+
+func (sink *tempDirSink) Remove(_ context.Context, file UploadedFile) error {
+	return os.Remove(file.Location)
+}
+
+type writerSink struct {
+	writer io.Writer
+}
+
+// NewWriterSink creates [UploadSink] which writes all files content to provided writer
+func NewWriterSink(writer io.Writer) UploadSink {
+	return &writerSink{
+		writer: writer,
+	}
+}
+
+func (sink *writerSink) Save(_ context.Context, _ *UploadedFile, content io.Reader) error {
+	_, err := io.Copy(sink.writer, content)
+	return err
+}
+
+// UploadStorage is external storage (S3, database, etc.) for uploaded files.
+type UploadStorage interface {
+	Put(ctx context.Context, key string, content io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+}
+
+type storageSink struct {
+	storage UploadStorage
+	key     func(file *UploadedFile) string
+}
+
+// NewStorageSink creates [UploadSink] which saves files to provided [UploadStorage].
+//
+// If key function is not provided, key will be random UUID with original file extension.
+//
+// [UploadedFile.Location] will be storage key
+func NewStorageSink(storage UploadStorage, key ...func(file *UploadedFile) string) UploadSink {
+	keyFunc := func(file *UploadedFile) string {
+		return uuid.NewString() + filepath.Ext(file.Filename)
+	}
+	if len(key) > 0 && key[0] != nil {
+		keyFunc = key[0]
+	}
+
+	return &storageSink{
+		storage: storage,
+		key:     keyFunc,
+	}
+}
+
+func (sink *storageSink) Save(ctx context.Context, file *UploadedFile, content io.Reader) error {
+	file.Location = sink.key(file)
+	return sink.storage.Put(ctx, file.Location, content, file.ContentType)
+}
+
+func (sink *storageSink) Remove(ctx context.Context, file UploadedFile) error {
+	return sink.storage.Delete(ctx, file.Location)
+}
+
+// sizeLimitReader returns error got from provided function when reader reads more than limit bytes
+type sizeLimitReader struct {
+	reader io.Reader
+	limit  int64
+	read   int64
+	err    func() error
+}
+
+type sizeLimitError struct {
+	err error
+}
+
+func (e *sizeLimitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sizeLimitError) Unwrap() error {
+	return e.err
+}
+
+func newSizeLimitReader(reader io.Reader, limit int64, err func() error) *sizeLimitReader {
+	return &sizeLimitReader{
+		reader: reader,
+		limit:  limit,
+		err:    err,
+	}
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	if r.read > r.limit {
+		return 0, &sizeLimitError{err: r.err()}
+	}
+
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n - int(r.read-r.limit), &sizeLimitError{err: r.err()}
+	}
+
+	return n, err
+}
+
+// violationReader saves limit violation to the stream, so next stream calls return the same error
+type violationReader struct {
+	reader io.Reader
+	stream *MultipartStream
+}
+
+func (r *violationReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, r.stream.fail(err)
+	}
+
+	return n, err
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
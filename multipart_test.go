@@ -0,0 +1,62 @@
+package echox
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/boostgo/httpx"
+	"github.com/labstack/echo/v4"
+)
+
+func TestStreamMultipartInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"not multipart", echo.MIMEApplicationJSON, "{}"},
+		{"no boundary", echo.MIMEMultipartForm, "--x\r\n"},
+		{"malformed body", echo.MIMEMultipartForm + "; boundary=x", "--x\r\nContent-Disposition form-data\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			request.Header.Set(echo.HeaderContentType, tt.contentType)
+			ctx := echo.New().NewContext(request, httptest.NewRecorder())
+
+			_, err := StreamMultipart(ctx, NewWriterSink(io.Discard))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			if status := httpx.StatusCodeByError(err); status != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %v", status, http.StatusBadRequest, err)
+			}
+		})
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	var body bytes.Buffer
+	body.WriteString("--x\r\nContent-Disposition: form-data; name=\"title\"\r\n\r\nreport\r\n" +
+		"--x\r\nContent-Disposition: form-data; name=\"file\"; filename=\"a.txt\"\r\n" +
+		"Content-Type: text/plain\r\n\r\nhello\r\n--x--\r\n")
+
+	request := httptest.NewRequest(http.MethodPost, "/", &body)
+	request.Header.Set(echo.HeaderContentType, echo.MIMEMultipartForm+"; boundary=x")
+	ctx := echo.New().NewContext(request, httptest.NewRecorder())
+
+	var content bytes.Buffer
+	if _, err := StreamMultipart(ctx, NewWriterSink(&content)); err != nil {
+		t.Fatal(err)
+	}
+
+	if content.String() != "hello" {
+		t.Fatalf("content = %q", content.String())
+	}
+}
@@ -0,0 +1,49 @@
+package echox
+
+import (
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	principalKey = "principal"
+
+	PrincipalSourceJWT    = "jwt"
+	PrincipalSourceAPIKey = "api_key"
+)
+
+// Principal is authenticated caller of the request set by auth middlewares ([JWTMiddleware], [APIKeyMiddleware]).
+type Principal struct {
+	// Subject is caller ID: user ID, service name, etc.
+	Subject string `json:"subject"`
+	// Scopes are granted scopes
+	Scopes []string `json:"scopes,omitempty"`
+	// Roles are caller roles
+	Roles []string `json:"roles,omitempty"`
+	// Source is auth method which authenticated the caller, e.g. "jwt" or "api_key"
+	Source string `json:"source"`
+	// Attributes are any other caller data (token claims, API key metadata, etc.)
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// HasScope returns true if the principal has provided scope
+func (principal Principal) HasScope(scope string) bool {
+	return slices.Contains(principal.Scopes, scope)
+}
+
+// HasRole returns true if the principal has provided role
+func (principal Principal) HasRole(role string) bool {
+	return slices.Contains(principal.Roles, role)
+}
+
+// SetPrincipal sets authenticated caller of the request
+func SetPrincipal(ctx echo.Context, principal Principal) {
+	Set(ctx, principalKey, principal)
+}
+
+// CurrentPrincipal returns authenticated caller of the request. Returns false if request is not authenticated
+func CurrentPrincipal(ctx echo.Context) (Principal, bool) {
+	principal, ok := Context(ctx).Value(principalKey).(Principal)
+	return principal, ok
+}
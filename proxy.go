@@ -0,0 +1,385 @@
+package echox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderForwarded      = "Forwarded"
+	HeaderXForwardedHost = "X-Forwarded-Host"
+
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+)
+
+// PrivateNetworks are loopback, private & link-local networks. Could be used as [TrustedProxyConfig] networks
+// if service is reachable only through proxies of the internal network
+var PrivateNetworks = []string{
+	"127.0.0.0/8",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"169.254.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// ProxyHeaders are forwarding headers set by trusted proxies
+type ProxyHeaders string
+
+const (
+	// ProxyHeadersXForwarded are "X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host" & "X-Real-IP" headers
+	ProxyHeadersXForwarded ProxyHeaders = "x-forwarded"
+	// ProxyHeadersForwarded is RFC 7239 "Forwarded" header
+	ProxyHeadersForwarded ProxyHeaders = "forwarded"
+)
+
+// TrustedProxyConfig describes proxies (load balancers, ingress, CDN) which forwarding headers are trusted
+type TrustedProxyConfig struct {
+	// Networks are CIDRs or IPs of trusted proxies
+	Networks []string
+	// Headers are forwarding headers set by proxies. Default is [ProxyHeadersXForwarded].
+	// Only one kind of headers is used, the other one is ignored, because proxies usually pass it from client as is
+	Headers ProxyHeaders
+}
+
+type trustedProxies struct {
+	prefixes []netip.Prefix
+	headers  ProxyHeaders
+}
+
+var (
+	_trustedProxies   = trustedProxies{headers: ProxyHeadersXForwarded}
+	_trustedProxiesMx sync.RWMutex
+)
+
+// RegisterTrustedProxies sets proxies which forwarding headers are trusted:
+//
+//	err := echox.RegisterTrustedProxies(echox.TrustedProxyConfig{
+//		Networks: echox.PrivateNetworks,
+//		Headers:  echox.ProxyHeadersForwarded,
+//	})
+//
+// Forwarding headers of requests from other addresses are ignored, so clients could not spoof their IP.
+// By default, there are no trusted proxies
+func RegisterTrustedProxies(config TrustedProxyConfig) error {
+	switch config.Headers {
+	case "":
+		config.Headers = ProxyHeadersXForwarded
+	case ProxyHeadersXForwarded, ProxyHeadersForwarded:
+	default:
+		return ErrTrustedProxy.SetError(fmt.Errorf("unknown proxy headers %q", config.Headers))
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(config.Networks))
+	for _, cidr := range config.Networks {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			return ErrTrustedProxy.SetError(err)
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	_trustedProxiesMx.Lock()
+	defer _trustedProxiesMx.Unlock()
+
+	_trustedProxies = trustedProxies{
+		prefixes: prefixes,
+		headers:  config.Headers,
+	}
+	return nil
+}
+
+// parsePrefix parses CIDR or single IP address
+func parsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+
+		return prefix.Masked(), nil
+	}
+
+	address, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	address = address.Unmap()
+	return netip.PrefixFrom(address, address.BitLen()), nil
+}
+
+func trustedProxy(address netip.Addr) bool {
+	_trustedProxiesMx.RLock()
+	defer _trustedProxiesMx.RUnlock()
+
+	return prefixesContain(_trustedProxies.prefixes, address)
+}
+
+func trustedProxyHeaders() ProxyHeaders {
+	_trustedProxiesMx.RLock()
+	defer _trustedProxiesMx.RUnlock()
+
+	return _trustedProxies.headers
+}
+
+// ClientIP returns real client IP address of the request.
+//
+// If request came from trusted proxy, client address is taken from registered forwarding headers
+// (see [TrustedProxyConfig]): the nearest not trusted address of the proxy chain
+func ClientIP(ctx echo.Context) string {
+	return clientIP(ctx.Request())
+}
+
+// clientIP is echo IP extractor, so ctx.RealIP() returns the same address as [ClientIP]
+func clientIP(request *http.Request) string {
+	remote, ok := remoteAddr(request)
+	if !ok {
+		host, _, _ := net.SplitHostPort(request.RemoteAddr)
+		return host
+	}
+
+	return forwardedRequest(request, remote).client.String()
+}
+
+func remoteAddr(request *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+
+	address, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return address.Unmap(), true
+}
+
+// forwarded is the request as it was sent by client to the edge proxy.
+// Empty proto & host mean that proxies did not provide them
+type forwarded struct {
+	client netip.Addr
+	proto  string
+	host   string
+}
+
+// forwardedHop is one proxy chain element: address of the proxy client and the request it got
+type forwardedHop struct {
+	address string
+	proto   string
+	host    string
+}
+
+// forwardedRequest resolves client address, scheme & host from forwarding headers of trusted proxies.
+//
+// Chain is walked from the nearest proxy to the client, so elements added by client itself are ignored
+// and scheme & host are taken from the same element as client address
+func forwardedRequest(request *http.Request, remote netip.Addr) forwarded {
+	result := forwarded{client: remote}
+	if !trustedProxy(remote) {
+		return result
+	}
+
+	headers := trustedProxyHeaders()
+	hops := forwardedHops(request, headers)
+	if len(hops) == 0 {
+		if headers == ProxyHeadersXForwarded {
+			if address, err := parseForwardedAddr(request.Header.Get(echo.HeaderXRealIP)); err == nil {
+				result.client = address
+			}
+		}
+
+		return result
+	}
+
+	for index := len(hops) - 1; index >= 0; index-- {
+		address, err := parseForwardedAddr(hops[index].address)
+		if err != nil {
+			// obfuscated or unknown address, chain could not be trusted further
+			break
+		}
+
+		result.client = address
+		result.proto = hops[index].proto
+		result.host = hops[index].host
+		if !trustedProxy(address) {
+			break
+		}
+	}
+
+	return result
+}
+
+// forwardedHops returns proxy chain from "Forwarded" or "X-Forwarded-*" headers
+func forwardedHops(request *http.Request, headers ProxyHeaders) []forwardedHop {
+	if headers == ProxyHeadersForwarded {
+		elements := forwardedElements(request)
+		hops := make([]forwardedHop, 0, len(elements))
+		for _, element := range elements {
+			hops = append(hops, forwardedHop{
+				address: element["for"],
+				proto:   element["proto"],
+				host:    element["host"],
+			})
+		}
+
+		return hops
+	}
+
+	chain := headerValues(request, echo.HeaderXForwardedFor)
+	protos := headerValues(request, echo.HeaderXForwardedProto)
+	hosts := headerValues(request, HeaderXForwardedHost)
+
+	hops := make([]forwardedHop, 0, len(chain))
+	for index, address := range chain {
+		hops = append(hops, forwardedHop{
+			address: address,
+			proto:   alignedValue(protos, len(chain), index),
+			host:    alignedValue(hosts, len(chain), index),
+		})
+	}
+
+	return hops
+}
+
+// alignedValue returns "X-Forwarded-Proto" or "X-Forwarded-Host" value for the chain element.
+// If every proxy appends value, lists are aligned with the chain. Otherwise, the nearest proxy value is used,
+// because values on the left could be sent by client
+func alignedValue(values []string, chainLength, index int) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	if len(values) == chainLength {
+		return values[index]
+	}
+
+	return values[len(values)-1]
+}
+
+func headerValues(request *http.Request, name string) []string {
+	values := make([]string, 0)
+	for _, header := range request.Header.Values(name) {
+		for _, value := range strings.Split(header, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values
+}
+
+// forwardedElements parses RFC 7239 "Forwarded" headers:
+//
+//	Forwarded: for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711"
+func forwardedElements(request *http.Request) []map[string]string {
+	elements := make([]map[string]string, 0)
+	for _, header := range request.Header.Values(HeaderForwarded) {
+		for _, element := range strings.Split(header, ",") {
+			pairs := make(map[string]string)
+			for _, pair := range strings.Split(element, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+
+				pairs[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+
+			if len(pairs) > 0 {
+				elements = append(elements, pairs)
+			}
+		}
+	}
+
+	return elements
+}
+
+// parseForwardedAddr parses address with optional port and brackets: "192.0.2.60", "192.0.2.60:80",
+// "[2001:db8::1]:4711" or "2001:db8::1"
+func parseForwardedAddr(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return netip.Addr{}, errors.New("empty address")
+	}
+
+	if address, err := netip.ParseAddr(strings.Trim(value, "[]")); err == nil {
+		return address.Unmap(), nil
+	}
+
+	addressPort, err := netip.ParseAddrPort(value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return addressPort.Addr().Unmap(), nil
+}
+
+// RequestScheme returns scheme ("http" or "https") of the request sent by client.
+// Behind trusted proxy scheme is taken from registered forwarding headers (see [TrustedProxyConfig])
+func RequestScheme(ctx echo.Context) string {
+	request := ctx.Request()
+	if remote, ok := remoteAddr(request); ok {
+		proto := strings.ToLower(forwardedRequest(request, remote).proto)
+		if proto == schemeHTTP || proto == schemeHTTPS {
+			return proto
+		}
+	}
+
+	if request.TLS != nil {
+		return schemeHTTPS
+	}
+
+	return schemeHTTP
+}
+
+// RequestHost returns host (with port if it is not default) of the request sent by client.
+// Behind trusted proxy host is taken from registered forwarding headers (see [TrustedProxyConfig])
+func RequestHost(ctx echo.Context) string {
+	request := ctx.Request()
+	if remote, ok := remoteAddr(request); ok {
+		if host := forwardedRequest(request, remote).host; validHost(host) {
+			return host
+		}
+	}
+
+	return request.Host
+}
+
+// BaseURL returns scheme & host of the request sent by client, e.g. "https://api.example.com",
+// for building absolute URLs (redirects, links, callbacks)
+func BaseURL(ctx echo.Context) string {
+	return RequestScheme(ctx) + "://" + RequestHost(ctx)
+}
+
+// validHost checks that host contains only host & port characters, so it is safe for URL building
+func validHost(host string) bool {
+	if host == "" || len(host) > 255 {
+		return false
+	}
+
+	for _, char := range host {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		case strings.ContainsRune(".-:[]_", char):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,183 @@
+package echox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerTrustedProxies(t *testing.T, config TrustedProxyConfig) {
+	t.Helper()
+
+	if err := RegisterTrustedProxies(config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = RegisterTrustedProxies(TrustedProxyConfig{})
+	})
+}
+
+func TestForwardedRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers ProxyHeaders
+		remote  string
+		request map[string][]string
+		ip      string
+		baseURL string
+	}{
+		{
+			name:    "untrusted remote",
+			remote:  "198.51.100.7:5000",
+			request: map[string][]string{"X-Forwarded-For": {"203.0.113.9"}, "X-Forwarded-Host": {"evil.example"}},
+			ip:      "198.51.100.7",
+			baseURL: "http://api.example.com",
+		},
+		{
+			name:   "x-forwarded",
+			remote: "10.0.0.2:5000",
+			request: map[string][]string{
+				"X-Forwarded-For":   {"203.0.113.9"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"shop.example.com"},
+			},
+			ip:      "203.0.113.9",
+			baseURL: "https://shop.example.com",
+		},
+		{
+			name:   "x-forwarded spoofed by client",
+			remote: "10.0.0.2:5000",
+			request: map[string][]string{
+				"X-Forwarded-For":   {"1.2.3.4, 203.0.113.9"},
+				"X-Forwarded-Proto": {"http, https"},
+				"X-Forwarded-Host":  {"evil.example, shop.example.com"},
+			},
+			ip:      "203.0.113.9",
+			baseURL: "https://shop.example.com",
+		},
+		{
+			name:   "x-forwarded host spoofed without address",
+			remote: "10.0.0.2:5000",
+			request: map[string][]string{
+				"X-Forwarded-For":  {"203.0.113.9"},
+				"X-Forwarded-Host": {"evil.example", "shop.example.com"},
+			},
+			ip:      "203.0.113.9",
+			baseURL: "http://shop.example.com",
+		},
+		{
+			name:   "x-forwarded chain of trusted proxies",
+			remote: "10.0.0.2:5000",
+			request: map[string][]string{
+				"X-Forwarded-For":   {"1.2.3.4, 203.0.113.9, 10.0.0.3"},
+				"X-Forwarded-Proto": {"http, https, http"},
+				"X-Forwarded-Host":  {"evil.example, shop.example.com, internal"},
+			},
+			ip:      "203.0.113.9",
+			baseURL: "https://shop.example.com",
+		},
+		{
+			name:   "forwarded ignored in x-forwarded mode",
+			remote: "10.0.0.2:5000",
+			request: map[string][]string{
+				"X-Forwarded-For": {"203.0.113.9"},
+				"Forwarded":       {"for=1.2.3.4;proto=https;host=evil.example"},
+			},
+			ip:      "203.0.113.9",
+			baseURL: "http://api.example.com",
+		},
+		{
+			name:    "x-real-ip",
+			remote:  "10.0.0.2:5000",
+			request: map[string][]string{"X-Real-Ip": {"203.0.113.9"}},
+			ip:      "203.0.113.9",
+			baseURL: "http://api.example.com",
+		},
+		{
+			name:    "forwarded",
+			headers: ProxyHeadersForwarded,
+			remote:  "10.0.0.2:5000",
+			request: map[string][]string{"Forwarded": {`for="[2001:db8::1]:4711";proto=https;host=shop.example.com`}},
+			ip:      "2001:db8::1",
+			baseURL: "https://shop.example.com",
+		},
+		{
+			name:    "forwarded spoofed by client",
+			headers: ProxyHeadersForwarded,
+			remote:  "10.0.0.2:5000",
+			request: map[string][]string{
+				"Forwarded": {"for=1.2.3.4;proto=http;host=evil.example", "for=203.0.113.9;proto=https;host=shop.example.com"},
+			},
+			ip:      "203.0.113.9",
+			baseURL: "https://shop.example.com",
+		},
+		{
+			name:    "forwarded chain of trusted proxies",
+			headers: ProxyHeadersForwarded,
+			remote:  "10.0.0.2:5000",
+			request: map[string][]string{
+				"Forwarded": {"for=1.2.3.4;host=evil.example, for=203.0.113.9;proto=https;host=shop.example.com, for=10.0.0.3;proto=http;host=internal"},
+			},
+			ip:      "203.0.113.9",
+			baseURL: "https://shop.example.com",
+		},
+		{
+			name:    "x-forwarded ignored in forwarded mode",
+			headers: ProxyHeadersForwarded,
+			remote:  "10.0.0.2:5000",
+			request: map[string][]string{
+				"X-Forwarded-For":   {"1.2.3.4"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"evil.example"},
+				"X-Real-Ip":         {"1.2.3.4"},
+			},
+			ip:      "10.0.0.2",
+			baseURL: "http://api.example.com",
+		},
+		{
+			name:    "obfuscated address",
+			headers: ProxyHeadersForwarded,
+			remote:  "10.0.0.2:5000",
+			request: map[string][]string{"Forwarded": {"for=203.0.113.9;host=evil.example, for=_hidden;host=evil.example"}},
+			ip:      "10.0.0.2",
+			baseURL: "http://api.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registerTrustedProxies(t, TrustedProxyConfig{
+				Networks: []string{"10.0.0.0/8"},
+				Headers:  tt.headers,
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Host = "api.example.com"
+			request.RemoteAddr = tt.remote
+			for name, values := range tt.request {
+				request.Header[name] = values
+			}
+
+			ctx := echo.New().NewContext(request, httptest.NewRecorder())
+			if ip := ClientIP(ctx); ip != tt.ip {
+				t.Errorf("client IP: expected %s, got %s", tt.ip, ip)
+			}
+
+			if baseURL := BaseURL(ctx); baseURL != tt.baseURL {
+				t.Errorf("base URL: expected %s, got %s", tt.baseURL, baseURL)
+			}
+		})
+	}
+}
+
+func TestRegisterTrustedProxies(t *testing.T) {
+	if err := RegisterTrustedProxies(TrustedProxyConfig{Headers: "x-real-ip"}); !isError(err, ErrTrustedProxy) {
+		t.Errorf("expected trusted proxy error for unknown headers, got %v", err)
+	}
+
+	if err := RegisterTrustedProxies(TrustedProxyConfig{Networks: []string{"10.0.0.0/33"}}); !isError(err, ErrTrustedProxy) {
+		t.Errorf("expected trusted proxy error for invalid network, got %v", err)
+	}
+}
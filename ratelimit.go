@@ -0,0 +1,415 @@
+package echox
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	RateLimitTokenBucket   = "token_bucket"
+	RateLimitSlidingWindow = "sliding_window"
+
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	rateLimitPolicyHeader    = "RateLimit-Policy"
+
+	defaultAPIKeyHeader = "X-API-Key"
+	defaultRateLimitKey = "ratelimit"
+)
+
+// KeyExtractor returns key of the request by which requests are limited (IP, user, API key, etc.).
+type KeyExtractor func(ctx echo.Context) (string, error)
+
+// KeyByIP limits requests by client IP address
+func KeyByIP() KeyExtractor {
+	return func(ctx echo.Context) (string, error) {
+		return ClientIP(ctx), nil
+	}
+}
+
+// KeyByHeader limits requests by provided request header value. Requests without header are limited together
+func KeyByHeader(name string) KeyExtractor {
+	return func(ctx echo.Context) (string, error) {
+		return ctx.Request().Header.Get(name), nil
+	}
+}
+
+// KeyByAPIKey limits requests by API key from provided header ("X-API-Key" by default)
+func KeyByAPIKey(header ...string) KeyExtractor {
+	name := defaultAPIKeyHeader
+	if len(header) > 0 && header[0] != "" {
+		name = header[0]
+	}
+
+	return KeyByHeader(name)
+}
+
+// KeyByContextValue limits requests by value from request context (e.g. user ID set by auth middleware)
+func KeyByContextValue(key any) KeyExtractor {
+	return func(ctx echo.Context) (string, error) {
+		value := Context(ctx).Value(key)
+		if value == nil {
+			return "", nil
+		}
+
+		return fmt.Sprint(value), nil
+	}
+}
+
+// KeyByRoute limits requests by route (method & path template), so all clients share one limit per route
+func KeyByRoute() KeyExtractor {
+	return func(ctx echo.Context) (string, error) {
+		return ctx.Request().Method + " " + ctx.Path(), nil
+	}
+}
+
+// KeyBy combines provided extractors, e.g. KeyBy(KeyByRoute(), KeyByIP()) limits every client on every route
+func KeyBy(extractors ...KeyExtractor) KeyExtractor {
+	return func(ctx echo.Context) (string, error) {
+		keys := make([]string, 0, len(extractors))
+		for _, extractor := range extractors {
+			key, err := extractor(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			keys = append(keys, key)
+		}
+
+		return strings.Join(keys, "|"), nil
+	}
+}
+
+// RateLimitPolicy describes how many requests are allowed in the window.
+type RateLimitPolicy struct {
+	// Algorithm is [RateLimitTokenBucket] (default) or [RateLimitSlidingWindow]
+	Algorithm string
+	// Limit is count of requests allowed in the window
+	Limit int
+	// Window is period of the limit. Default is 1 minute
+	Window time.Duration
+	// Burst is token bucket capacity. Default is Limit
+	Burst int
+}
+
+func (policy RateLimitPolicy) normalize() RateLimitPolicy {
+	if policy.Algorithm == "" {
+		policy.Algorithm = RateLimitTokenBucket
+	}
+
+	if policy.Window <= 0 {
+		policy.Window = time.Minute
+	}
+
+	if policy.Burst <= 0 {
+		policy.Burst = policy.Limit
+	}
+
+	return policy
+}
+
+// String returns policy in "RateLimit-Policy" header format
+func (policy RateLimitPolicy) String() string {
+	value := strconv.Itoa(policy.Limit) + ";w=" + strconv.Itoa(int(math.Ceil(policy.Window.Seconds())))
+	if policy.Algorithm == RateLimitTokenBucket && policy.Burst != policy.Limit {
+		value += ";burst=" + strconv.Itoa(policy.Burst)
+	}
+
+	return value
+}
+
+// RateLimitResult is result of one request check.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is time until limit is fully restored
+	Reset time.Duration
+	// RetryAfter is time until next request would be allowed (for rejected requests)
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps limiters state. Allow must be atomic: check & count request in one operation.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitConfig describes [RateLimitMiddleware].
+type RateLimitConfig struct {
+	// Policy is default policy of all requests
+	Policy RateLimitPolicy
+	// Routes are policies of concrete routes by keys like "GET /users/:id" or "/users/:id" (any method).
+	// Every route policy has its own counters
+	Routes map[string]RateLimitPolicy
+	// KeyExtractor returns request key. Default is [KeyByIP]
+	KeyExtractor KeyExtractor
+	// Store keeps counters. Default is in-memory store created by [NewMemoryRateLimitStore]
+	Store RateLimitStore
+	// Name separates counters of different limiters which use one store. Default is "ratelimit"
+	Name string
+	// Skip returns true for requests which must not be limited
+	Skip func(ctx echo.Context) bool
+}
+
+func newRateLimitConfig(cfg ...RateLimitConfig) RateLimitConfig {
+	var config RateLimitConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	config.Policy = config.Policy.normalize()
+
+	routes := make(map[string]RateLimitPolicy, len(config.Routes))
+	for route, policy := range config.Routes {
+		routes[route] = policy.normalize()
+	}
+	config.Routes = routes
+
+	if config.KeyExtractor == nil {
+		config.KeyExtractor = KeyByIP()
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+
+	if config.Name == "" {
+		config.Name = defaultRateLimitKey
+	}
+
+	return config
+}
+
+// policy returns route policy (if exist) or default policy with its counters key prefix
+func (config RateLimitConfig) policy(ctx echo.Context) (RateLimitPolicy, string) {
+	method := ctx.Request().Method
+	for _, route := range []string{method + " " + ctx.Path(), ctx.Path()} {
+		if policy, ok := config.Routes[route]; ok {
+			return policy, config.Name + ":" + route
+		}
+	}
+
+	return config.Policy, config.Name
+}
+
+// RateLimitMiddleware limits requests count by keys returned by key extractor.
+//
+// Use it as global, group or route middleware (with own config) or set route policies in config.
+//
+// Every response has RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset & RateLimit-Policy headers.
+// Rejected requests get 429 Too Many Requests with Retry-After header.
+//
+// If store fails, requests are allowed (and error is logged)
+func RateLimitMiddleware(cfg ...RateLimitConfig) echo.MiddlewareFunc {
+	config := newRateLimitConfig(cfg...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if config.Skip != nil && config.Skip(ctx) {
+				return next(ctx)
+			}
+
+			policy, prefix := config.policy(ctx)
+			if policy.Limit <= 0 {
+				return next(ctx)
+			}
+
+			key, err := config.KeyExtractor(ctx)
+			if err != nil {
+				return Error(ctx, err)
+			}
+
+			result, err := config.Store.Allow(Context(ctx), prefix+":"+key, policy, time.Now())
+			if err != nil {
+				log.
+					Error().
+					Ctx(Context(ctx)).
+					Err(err).
+					Str("method", ctx.Request().Method).
+					Msg("Rate limit store")
+
+				return next(ctx)
+			}
+
+			header := ctx.Response().Header()
+			header.Set(rateLimitLimitHeader, strconv.Itoa(result.Limit))
+			header.Set(rateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+			header.Set(rateLimitResetHeader, durationSeconds(result.Reset))
+			header.Set(rateLimitPolicyHeader, policy.String())
+
+			if !result.Allowed {
+				header.Set(echo.HeaderRetryAfter, durationSeconds(result.RetryAfter))
+				return Error(ctx, newRateLimitError(result))
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// durationSeconds returns duration in whole seconds rounded up
+func durationSeconds(duration time.Duration) string {
+	if duration <= 0 {
+		return "0"
+	}
+
+	return strconv.FormatInt(int64(math.Ceil(duration.Seconds())), 10)
+}
+
+// MemoryRateLimitStore is in-memory [RateLimitStore]. It works only within one service instance.
+type MemoryRateLimitStore struct {
+	states    map[string]*rateLimitState
+	lastSweep time.Time
+	mx        sync.Mutex
+}
+
+type rateLimitState struct {
+	// token bucket
+	tokens float64
+	last   time.Time
+
+	// sliding window
+	windowStart time.Time
+	current     int
+	previous    int
+
+	expiresAt time.Time
+}
+
+// NewMemoryRateLimitStore creates [MemoryRateLimitStore]
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		states: make(map[string]*rateLimitState),
+	}
+}
+
+func (store *MemoryRateLimitStore) Allow(
+	_ context.Context,
+	key string,
+	policy RateLimitPolicy,
+	now time.Time,
+) (RateLimitResult, error) {
+	policy = policy.normalize()
+	if policy.Limit <= 0 {
+		return RateLimitResult{Allowed: true}, nil
+	}
+
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	store.sweep(now)
+
+	state, ok := store.states[key]
+	if !ok {
+		state = &rateLimitState{
+			tokens:      float64(policy.Burst),
+			last:        now,
+			windowStart: now,
+		}
+		store.states[key] = state
+	}
+
+	if policy.Algorithm == RateLimitSlidingWindow {
+		state.expiresAt = now.Add(2 * policy.Window)
+		return state.slidingWindow(policy, now), nil
+	}
+
+	// after this time bucket is full again, so state is the same as new one
+	state.expiresAt = now.Add(time.Duration(float64(policy.Window) * float64(policy.Burst) / float64(policy.Limit)))
+	return state.tokenBucket(policy, now), nil
+}
+
+func (state *rateLimitState) tokenBucket(policy RateLimitPolicy, now time.Time) RateLimitResult {
+	// tokens per second
+	rate := float64(policy.Limit) / policy.Window.Seconds()
+
+	if elapsed := now.Sub(state.last).Seconds(); elapsed > 0 {
+		state.tokens = math.Min(float64(policy.Burst), state.tokens+elapsed*rate)
+		state.last = now
+	}
+
+	result := RateLimitResult{
+		Limit: policy.Burst,
+	}
+
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - state.tokens) / rate)
+	}
+
+	result.Remaining = int(state.tokens)
+	result.Reset = secondsDuration((float64(policy.Burst) - state.tokens) / rate)
+	return result
+}
+
+func (state *rateLimitState) slidingWindow(policy RateLimitPolicy, now time.Time) RateLimitResult {
+	// shift windows
+	if passed := int(now.Sub(state.windowStart) / policy.Window); passed > 0 {
+		state.previous = 0
+		if passed == 1 {
+			state.previous = state.current
+		}
+
+		state.current = 0
+		state.windowStart = state.windowStart.Add(time.Duration(passed) * policy.Window)
+	}
+
+	elapsed := now.Sub(state.windowStart)
+	weight := 1 - float64(elapsed)/float64(policy.Window)
+	estimate := float64(state.previous)*weight + float64(state.current)
+
+	result := RateLimitResult{
+		Limit: policy.Limit,
+		Reset: policy.Window - elapsed,
+	}
+
+	if estimate+1 <= float64(policy.Limit) {
+		state.current++
+		estimate++
+		result.Allowed = true
+	} else {
+		// previous window weight decreases over time, so wait till estimate is below the limit
+		result.RetryAfter = policy.Window - elapsed
+		if state.previous > 0 {
+			excess := estimate + 1 - float64(policy.Limit)
+			wait := time.Duration(excess / float64(state.previous) * float64(policy.Window))
+			result.RetryAfter = min(wait, result.RetryAfter)
+		}
+	}
+
+	if state.previous > 0 {
+		result.Reset += policy.Window
+	}
+
+	result.Remaining = max(0, policy.Limit-int(math.Ceil(estimate)))
+	return result
+}
+
+// sweep removes expired states not often than once a minute
+func (store *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < time.Minute {
+		return
+	}
+
+	store.lastSweep = now
+	for key, state := range store.states {
+		if now.After(state.expiresAt) {
+			delete(store.states, key)
+		}
+	}
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
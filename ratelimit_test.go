@@ -0,0 +1,110 @@
+package echox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := echo.New()
+	handler.GET("/orders", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}, RateLimitMiddleware(RateLimitConfig{
+		Policy: RateLimitPolicy{Limit: 2, Window: time.Minute},
+	}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	tests := []struct {
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{http.StatusOK, "1", ""},
+		{http.StatusOK, "0", ""},
+		{http.StatusTooManyRequests, "0", "30"},
+	}
+
+	for i, tt := range tests {
+		recorder := send("203.0.113.9:1000")
+		if recorder.Code != tt.status {
+			t.Fatalf("request %d: status = %d, want %d", i, recorder.Code, tt.status)
+		}
+
+		header := recorder.Header()
+		if header.Get(rateLimitLimitHeader) != "2" || header.Get(rateLimitPolicyHeader) != "2;w=60" {
+			t.Fatalf("request %d: limit = %q, policy = %q", i, header.Get(rateLimitLimitHeader), header.Get(rateLimitPolicyHeader))
+		}
+
+		if header.Get(rateLimitRemainingHeader) != tt.remaining || header.Get(echo.HeaderRetryAfter) != tt.retryAfter {
+			t.Fatalf("request %d: remaining = %q, retry after = %q", i, header.Get(rateLimitRemainingHeader), header.Get(echo.HeaderRetryAfter))
+		}
+	}
+
+	// other client has its own bucket
+	if recorder := send("203.0.113.10:1000"); recorder.Code != http.StatusOK {
+		t.Fatalf("other client status = %d", recorder.Code)
+	}
+}
+
+func TestMemoryRateLimitStoreTokenBucket(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	policy := RateLimitPolicy{Limit: 60, Window: time.Minute, Burst: 3}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		result, _ := store.Allow(context.Background(), "key", policy, now)
+		if !result.Allowed || result.Limit != 3 || result.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i, result)
+		}
+	}
+
+	result, _ := store.Allow(context.Background(), "key", policy, now)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Fatalf("rejected request: %+v", result)
+	}
+
+	// one token is refilled per second
+	result, _ = store.Allow(context.Background(), "key", policy, now.Add(time.Second))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("refilled request: %+v", result)
+	}
+
+	if policy.normalize().String() != "60;w=60;burst=3" {
+		t.Fatalf("policy = %s", policy.normalize().String())
+	}
+}
+
+func TestMemoryRateLimitStoreSlidingWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	policy := RateLimitPolicy{Algorithm: RateLimitSlidingWindow, Limit: 2, Window: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Allow(context.Background(), "key", policy, now); !result.Allowed {
+			t.Fatalf("request %d: %+v", i, result)
+		}
+	}
+
+	result, _ := store.Allow(context.Background(), "key", policy, now.Add(30*time.Second))
+	if result.Allowed || result.RetryAfter != 30*time.Second {
+		t.Fatalf("rejected request: %+v", result)
+	}
+
+	// half of the previous window is counted: 2 * 0.5 = 1 request
+	result, _ = store.Allow(context.Background(), "key", policy, now.Add(90*time.Second))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("next window request: %+v", result)
+	}
+}
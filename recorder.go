@@ -0,0 +1,56 @@
+package echox
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// responseRecorder writes response to the inner writer and keeps copy of status & body
+type responseRecorder struct {
+	inner  http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func newResponseRecorder(inner http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{
+		inner: inner,
+	}
+}
+
+func (recorder *responseRecorder) Header() http.Header {
+	return recorder.inner.Header()
+}
+
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	recorder.status = statusCode
+	recorder.inner.WriteHeader(statusCode)
+}
+
+func (recorder *responseRecorder) Write(b []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+
+	recorder.body.Write(b)
+	return recorder.inner.Write(b)
+}
+
+func (recorder *responseRecorder) Flush() {
+	if flusher, ok := recorder.inner.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.inner
+}
+
+// Status returns written status code or 200 if nothing written
+func (recorder *responseRecorder) Status() int {
+	if recorder.status == 0 {
+		return http.StatusOK
+	}
+
+	return recorder.status
+}
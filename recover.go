@@ -0,0 +1,148 @@
+package echox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/boostgo/configx"
+	"github.com/boostgo/errorx"
+	"github.com/boostgo/log"
+	"github.com/boostgo/trace"
+	"github.com/labstack/echo/v4"
+)
+
+// PanicReport describes recovered handler panic.
+type PanicReport struct {
+	Value   any
+	Stack   []byte
+	Method  string
+	Route   string
+	URI     string
+	TraceID string
+	Time    time.Time
+}
+
+// PanicReporter receives recovered panics, e.g. to send them to error tracking service.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, report PanicReport)
+}
+
+// PanicReporterFunc is function implementation of [PanicReporter]
+type PanicReporterFunc func(ctx context.Context, report PanicReport)
+
+func (fn PanicReporterFunc) ReportPanic(ctx context.Context, report PanicReport) {
+	fn(ctx, report)
+}
+
+var (
+	_panicReporters   = make([]PanicReporter, 0)
+	_panicReportersMx sync.RWMutex
+)
+
+// RegisterPanicReporter adds reporter which is called on every recovered panic
+func RegisterPanicReporter(reporter PanicReporter) {
+	if reporter == nil {
+		return
+	}
+
+	_panicReportersMx.Lock()
+	defer _panicReportersMx.Unlock()
+
+	_panicReporters = append(_panicReporters, reporter)
+}
+
+// RecoverMiddleware recovers handler panics: logs panic with stack, route, method & trace ID,
+// calls registered panic reporters and returns 500 failure.
+//
+// Failure contains panic value & stack only if service is not in production mode.
+//
+// Errors returned by handlers are rendered by [Error] if response is not written yet
+func RecoverMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if err := callRecovered(ctx, next); err != nil {
+				if ctx.Response().Committed {
+					return err
+				}
+
+				return Error(ctx, err)
+			}
+
+			return nil
+		}
+	}
+}
+
+// callRecovered calls handler and converts its panic to error
+func callRecovered(ctx echo.Context, next echo.HandlerFunc) (err error) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+
+		// http.ErrAbortHandler aborts response on purpose
+		if value == http.ErrAbortHandler { //nolint:errorlint
+			panic(value)
+		}
+
+		err = recoverPanic(ctx, value, debug.Stack())
+	}()
+
+	return next(ctx)
+}
+
+func recoverPanic(ctx echo.Context, value any, stack []byte) error {
+	report := PanicReport{
+		Value:   value,
+		Stack:   stack,
+		Method:  ctx.Request().Method,
+		Route:   ctx.Path(),
+		URI:     ctx.Request().RequestURI,
+		TraceID: trace.Get(Context(ctx)),
+		Time:    time.Now(),
+	}
+
+	log.
+		Error().
+		Ctx(Context(ctx)).
+		Str("panic", fmt.Sprint(value)).
+		Str("method", report.Method).
+		Str("route", report.Route).
+		Str("trace_id", report.TraceID).
+		Str("stack", string(stack)).
+		Msg("Panic recovered: " + report.URI)
+
+	_panicReportersMx.RLock()
+	reporters := _panicReporters
+	_panicReportersMx.RUnlock()
+
+	for _, reporter := range reporters {
+		reportPanic(Context(ctx), reporter, report)
+	}
+
+	if configx.Production() {
+		return errorx.ErrPanicRecover
+	}
+
+	return newPanicError(value, stack)
+}
+
+// reportPanic calls reporter and ignores its own panic
+func reportPanic(ctx context.Context, reporter PanicReporter, report PanicReport) {
+	defer func() {
+		if value := recover(); value != nil {
+			log.
+				Error().
+				Ctx(ctx).
+				Str("panic", fmt.Sprint(value)).
+				Msg("Panic reporter failed")
+		}
+	}()
+
+	reporter.ReportPanic(ctx, report)
+}
@@ -0,0 +1,298 @@
+package echox
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	cspNonceKey         = "csp-nonce"
+	cspNoncePlaceholder = "{nonce}"
+	cspNonceLength      = 16
+	maxCSPReportSize    = 64 << 10 // 64KB
+	cspReportEndpoint   = "csp-endpoint"
+
+	HeaderPermissionsPolicy         = "Permissions-Policy"
+	HeaderCrossOriginOpenerPolicy   = "Cross-Origin-Opener-Policy"
+	HeaderCrossOriginEmbedderPolicy = "Cross-Origin-Embedder-Policy"
+	HeaderCrossOriginResourcePolicy = "Cross-Origin-Resource-Policy"
+	HeaderReportingEndpoints        = "Reporting-Endpoints"
+)
+
+// SecurityHeadersConfig describes [SecurityHeadersMiddleware]. Empty values remove the header,
+// so config of group middleware fully overrides config of global middleware.
+// Use [StrictSecurityHeaders] or [RelaxedSecurityHeaders] as the base.
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge is "Strict-Transport-Security" max age. Zero disables HSTS
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// ContentSecurityPolicy is CSP. Placeholder "{nonce}" is replaced by request nonce available by [CSPNonce]
+	ContentSecurityPolicy string
+	// CSPReportOnly sends CSP as "Content-Security-Policy-Report-Only", so violations are reported but not blocked
+	CSPReportOnly bool
+	// CSPReportURI is endpoint of violation reports, e.g. route with [CSPReportHandler].
+	// It is set by "report-uri" directive & by "report-to" directive with "Reporting-Endpoints" header,
+	// so both legacy & Reporting API browsers send reports
+	CSPReportURI              string
+	ContentTypeOptions        string
+	FrameOptions              string
+	ReferrerPolicy            string
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginEmbedderPolicy string
+	CrossOriginResourcePolicy string
+}
+
+// StrictSecurityHeaders returns config for APIs & applications without third party resources:
+// everything is allowed from the same origin only, scripts & styles require nonce
+func StrictSecurityHeaders() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		HSTSMaxAge:            2 * 365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'; " +
+			"script-src 'self' 'nonce-{nonce}'; " +
+			"style-src 'self' 'nonce-{nonce}'; " +
+			"img-src 'self' data:; " +
+			"object-src 'none'; " +
+			"base-uri 'self'; " +
+			"form-action 'self'; " +
+			"frame-ancestors 'none'",
+		ContentTypeOptions:        "nosniff",
+		FrameOptions:              "DENY",
+		ReferrerPolicy:            "no-referrer",
+		PermissionsPolicy:         "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginEmbedderPolicy: "require-corp",
+		CrossOriginResourcePolicy: "same-origin",
+	}
+}
+
+// RelaxedSecurityHeaders returns config for applications which use third party resources over HTTPS
+// (CDN, analytics, embeds) and could be framed by the same origin
+func RelaxedSecurityHeaders() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		HSTSMaxAge: 365 * 24 * time.Hour,
+		ContentSecurityPolicy: "default-src 'self' https:; " +
+			"script-src 'self' 'nonce-{nonce}' https:; " +
+			"style-src 'self' 'unsafe-inline' https:; " +
+			"img-src 'self' data: https:; " +
+			"object-src 'none'; " +
+			"base-uri 'self'; " +
+			"frame-ancestors 'self'",
+		ContentTypeOptions:        "nosniff",
+		FrameOptions:              "SAMEORIGIN",
+		ReferrerPolicy:            "strict-origin-when-cross-origin",
+		PermissionsPolicy:         "camera=(), microphone=(), geolocation=()",
+		CrossOriginOpenerPolicy:   "same-origin-allow-popups",
+		CrossOriginResourcePolicy: "same-site",
+	}
+}
+
+func (config SecurityHeadersConfig) hsts() string {
+	if config.HSTSMaxAge <= 0 {
+		return ""
+	}
+
+	value := "max-age=" + strconv.FormatInt(int64(config.HSTSMaxAge.Seconds()), 10)
+	if config.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+
+	if config.HSTSPreload {
+		value += "; preload"
+	}
+
+	return value
+}
+
+// SecurityHeadersMiddleware sets security headers (HSTS, CSP, X-Content-Type-Options, X-Frame-Options,
+// Referrer-Policy, Permissions-Policy, COOP, COEP & CORP). Default config is [StrictSecurityHeaders].
+//
+// Middleware could be used globally and overridden by group or route middleware with another config.
+// CSP nonce is generated once per request and shared by all security headers middlewares
+func SecurityHeadersMiddleware(cfg ...SecurityHeadersConfig) echo.MiddlewareFunc {
+	config := StrictSecurityHeaders()
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	hsts := config.hsts()
+	csp := config.ContentSecurityPolicy
+	var reportingEndpoints string
+	if csp != "" && config.CSPReportURI != "" {
+		csp += "; report-uri " + config.CSPReportURI + "; report-to " + cspReportEndpoint
+		reportingEndpoints = cspReportEndpoint + `="` + config.CSPReportURI + `"`
+	}
+
+	cspHeader, otherCSPHeader := echo.HeaderContentSecurityPolicy, echo.HeaderContentSecurityPolicyReportOnly
+	if config.CSPReportOnly {
+		cspHeader, otherCSPHeader = otherCSPHeader, cspHeader
+	}
+
+	headers := []struct {
+		name  string
+		value string
+	}{
+		{echo.HeaderStrictTransportSecurity, hsts},
+		{echo.HeaderXContentTypeOptions, config.ContentTypeOptions},
+		{echo.HeaderXFrameOptions, config.FrameOptions},
+		{echo.HeaderReferrerPolicy, config.ReferrerPolicy},
+		{HeaderPermissionsPolicy, config.PermissionsPolicy},
+		{HeaderCrossOriginOpenerPolicy, config.CrossOriginOpenerPolicy},
+		{HeaderCrossOriginEmbedderPolicy, config.CrossOriginEmbedderPolicy},
+		{HeaderCrossOriginResourcePolicy, config.CrossOriginResourcePolicy},
+		{HeaderReportingEndpoints, reportingEndpoints},
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			header := ctx.Response().Header()
+			for _, h := range headers {
+				if h.value == "" {
+					header.Del(h.name)
+					continue
+				}
+
+				header.Set(h.name, h.value)
+			}
+
+			header.Del(otherCSPHeader)
+			if csp == "" {
+				header.Del(cspHeader)
+				return next(ctx)
+			}
+
+			policy := csp
+			if strings.Contains(policy, cspNoncePlaceholder) {
+				nonce, err := cspNonce(ctx)
+				if err != nil {
+					return Error(ctx, err)
+				}
+
+				policy = strings.ReplaceAll(policy, cspNoncePlaceholder, nonce)
+			}
+
+			header.Set(cspHeader, policy)
+			return next(ctx)
+		}
+	}
+}
+
+// CSPNonce returns CSP nonce of the request for inline scripts & styles:
+//
+//	<script nonce="{{ .Nonce }}">...</script>
+//
+// Returns empty string if CSP set by [SecurityHeadersMiddleware] has no nonce
+func CSPNonce(ctx echo.Context) string {
+	nonce, _ := Context(ctx).Value(cspNonceKey).(string)
+	return nonce
+}
+
+// cspNonce returns nonce of the request or generates new one
+func cspNonce(ctx echo.Context) (string, error) {
+	if nonce := CSPNonce(ctx); nonce != "" {
+		return nonce, nil
+	}
+
+	blob := make([]byte, cspNonceLength)
+	if _, err := rand.Read(blob); err != nil {
+		return "", ErrSecurityHeaders.SetError(err)
+	}
+
+	nonce := base64.StdEncoding.EncodeToString(blob)
+	Set(ctx, cspNonceKey, nonce)
+	return nonce, nil
+}
+
+// cspViolation is CSP violation from "application/csp-report" (report-uri) or
+// "application/reports+json" (Reporting API) body
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	DocumentURL        string `json:"documentURL"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	BlockedURI         string `json:"blocked-uri"`
+	BlockedURL         string `json:"blockedURL"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	Disposition        string `json:"disposition"`
+}
+
+// CSPReportHandler returns handler of CSP violation reports which logs every violation.
+// Supports "report-uri" reports & Reporting API reports. Responds with 204 No Content
+func CSPReportHandler() echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		blob, err := io.ReadAll(io.LimitReader(ctx.Request().Body, maxCSPReportSize))
+		if err != nil {
+			return Error(ctx, wrapError(ErrReadRequestBody, err))
+		}
+
+		violations, err := parseCSPReport(blob)
+		if err != nil {
+			return Error(ctx, ErrCSPReport)
+		}
+
+		for _, violation := range violations {
+			log.Warn().
+				Ctx(Context(ctx)).
+				Str("document", firstNotEmpty(violation.DocumentURI, violation.DocumentURL)).
+				Str("directive", firstNotEmpty(violation.EffectiveDirective, violation.ViolatedDirective)).
+				Str("blocked", firstNotEmpty(violation.BlockedURI, violation.BlockedURL)).
+				Str("source", violation.SourceFile).
+				Int("line", violation.LineNumber).
+				Str("disposition", violation.Disposition).
+				Str("user_agent", ctx.Request().UserAgent()).
+				Msg("CSP violation")
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	}
+}
+
+func parseCSPReport(blob []byte) ([]cspViolation, error) {
+	// report-uri: {"csp-report": {...}}
+	var legacy struct {
+		Report *cspViolation `json:"csp-report"`
+	}
+	if err := json.Unmarshal(blob, &legacy); err == nil && legacy.Report != nil {
+		return []cspViolation{*legacy.Report}, nil
+	}
+
+	// Reporting API: [{"type": "csp-violation", "body": {...}}]
+	var reports []struct {
+		Type string       `json:"type"`
+		Body cspViolation `json:"body"`
+	}
+	if err := json.Unmarshal(blob, &reports); err != nil {
+		return nil, err
+	}
+
+	violations := make([]cspViolation, 0, len(reports))
+	for _, report := range reports {
+		if report.Type == "csp-violation" {
+			violations = append(violations, report.Body)
+		}
+	}
+
+	return violations, nil
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -19,6 +19,9 @@ import (
 func run(address string) error {
 	handler := echo.New()
 
+	// client IP is taken from forwarding headers of trusted proxies only
+	handler.IPExtractor = clientIP
+
 	// add CORS middleware
 	handler.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{"*"},
@@ -26,14 +29,6 @@ func run(address string) error {
 		AllowCredentials: true,
 	}))
 
-	// add recover middleware
-	handler.Use(RecoverMiddleware())
-
-	// register not found route
-	handler.RouteNotFound("*", func(ctx echo.Context) error {
-		return Error(ctx, newRouteNotFoundError(ctx.Request()))
-	})
-
 	// add trace middleware
 	if trace.AmIMaster() {
 		handler.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
@@ -47,6 +42,14 @@ func run(address string) error {
 		}))
 	}
 
+	// add recover middleware
+	handler.Use(RecoverMiddleware())
+
+	// register not found route
+	handler.RouteNotFound("*", func(ctx echo.Context) error {
+		return Error(ctx, newRouteNotFoundError(ctx.Request()))
+	})
+
 	// set middlewares
 	for _, mid := range _middlewares {
 		handler.Use(mid)
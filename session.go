@@ -0,0 +1,425 @@
+package echox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	sessionKey = "session"
+
+	defaultSessionCookie          = "session"
+	defaultSessionIdleTimeout     = 30 * time.Minute
+	defaultSessionAbsoluteTimeout = 24 * time.Hour
+	sessionIDLength               = 32
+	// sessionTouchInterval limits saves of not modified sessions which are done only to prolong idle timeout
+	sessionTouchInterval = time.Minute
+)
+
+// SessionRecord is session state kept in [SessionStore].
+type SessionRecord struct {
+	ID         string                     `json:"id"`
+	Values     map[string]json.RawMessage `json:"values"`
+	CreatedAt  time.Time                  `json:"created_at"`
+	LastSeenAt time.Time                  `json:"last_seen_at"`
+}
+
+// SessionStore keeps sessions. Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Load returns session by the cookie value. Returns false if there is no such session
+	Load(ctx context.Context, value string) (SessionRecord, bool, error)
+	// Save saves session and returns cookie value. Server side stores return session ID,
+	// cookie store returns encrypted session
+	Save(ctx context.Context, record SessionRecord, ttl time.Duration) (string, error)
+	// Delete removes session by the cookie value
+	Delete(ctx context.Context, value string) error
+}
+
+// SessionConfig describes [SessionMiddleware].
+type SessionConfig struct {
+	// Store keeps sessions. Default is in-memory store created by [NewMemorySessionStore]
+	Store SessionStore
+	// Cookie is session cookie name. Default is "session"
+	Cookie string
+	// Domain & Path of session cookie. Default path is "/"
+	Domain string
+	Path   string
+	// Insecure allows session cookie over plain HTTP (for local development)
+	Insecure bool
+	// SameSite of session cookie. Default is Lax
+	SameSite http.SameSite
+	// IdleTimeout expires session which was not used for the period. Default is 30 minutes
+	IdleTimeout time.Duration
+	// AbsoluteTimeout expires session after the period since creation anyway. Default is 24 hours
+	AbsoluteTimeout time.Duration
+}
+
+func newSessionConfig(cfg ...SessionConfig) SessionConfig {
+	var config SessionConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemorySessionStore()
+	}
+
+	if config.Cookie == "" {
+		config.Cookie = defaultSessionCookie
+	}
+
+	if config.Path == "" {
+		config.Path = "/"
+	}
+
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultSessionIdleTimeout
+	}
+
+	if config.AbsoluteTimeout <= 0 {
+		config.AbsoluteTimeout = defaultSessionAbsoluteTimeout
+	}
+
+	return config
+}
+
+// SessionData is session of the request available by [Session] function.
+type SessionData struct {
+	record    SessionRecord
+	value     string // current cookie value
+	isNew     bool
+	modified  bool
+	rotated   string // cookie value of session before rotation
+	destroyed bool
+	committed bool
+	mx        sync.RWMutex
+}
+
+// ID returns session ID
+func (session *SessionData) ID() string {
+	session.mx.RLock()
+	defer session.mx.RUnlock()
+
+	return session.record.ID
+}
+
+// IsNew returns true if session was created by the request
+func (session *SessionData) IsNew() bool {
+	session.mx.RLock()
+	defer session.mx.RUnlock()
+
+	return session.isNew
+}
+
+// CreatedAt returns session creation time
+func (session *SessionData) CreatedAt() time.Time {
+	session.mx.RLock()
+	defer session.mx.RUnlock()
+
+	return session.record.CreatedAt
+}
+
+// Get decodes value by key to provided pointer. Returns false if there is no value
+func (session *SessionData) Get(key string, export any) (bool, error) {
+	session.mx.RLock()
+	defer session.mx.RUnlock()
+
+	raw, ok := session.record.Values[key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(raw, export); err != nil {
+		return false, ErrSession.SetError(err)
+	}
+
+	return true, nil
+}
+
+// Set sets value by key. Value must be JSON serializable
+func (session *SessionData) Set(key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return ErrSession.SetError(err)
+	}
+
+	session.mx.Lock()
+	defer session.mx.Unlock()
+
+	session.record.Values[key] = raw
+	session.modified = true
+	return nil
+}
+
+// Delete removes value by key
+func (session *SessionData) Delete(key string) {
+	session.mx.Lock()
+	defer session.mx.Unlock()
+
+	if _, ok := session.record.Values[key]; ok {
+		delete(session.record.Values, key)
+		session.modified = true
+	}
+}
+
+// Clear removes all values
+func (session *SessionData) Clear() {
+	session.mx.Lock()
+	defer session.mx.Unlock()
+
+	session.record.Values = make(map[string]json.RawMessage)
+	session.modified = true
+}
+
+// Rotate changes session ID keeping its values. Must be called on privilege change (login, logout,
+// role change) to prevent session fixation
+func (session *SessionData) Rotate() error {
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+
+	session.mx.Lock()
+	defer session.mx.Unlock()
+
+	if !session.isNew && session.rotated == "" {
+		session.rotated = session.value
+	}
+
+	session.record.ID = id
+	session.modified = true
+	return nil
+}
+
+// Destroy removes session from the store and expires session cookie
+func (session *SessionData) Destroy() {
+	session.mx.Lock()
+	defer session.mx.Unlock()
+
+	session.record.Values = make(map[string]json.RawMessage)
+	session.destroyed = true
+}
+
+// Session returns session of the request created by [SessionMiddleware].
+// If middleware is not used, returns empty session which is never saved
+func Session(ctx echo.Context) *SessionData {
+	if session, ok := Context(ctx).Value(sessionKey).(*SessionData); ok {
+		return session
+	}
+
+	return &SessionData{
+		record: SessionRecord{
+			Values: make(map[string]json.RawMessage),
+		},
+		isNew: true,
+	}
+}
+
+// SessionValue returns session value by key converted to provided type.
+// Returns false if there is no value or it could not be converted
+func SessionValue[T any](ctx echo.Context, key string) (T, bool) {
+	var value T
+	ok, err := Session(ctx).Get(key, &value)
+	if err != nil || !ok {
+		var empty T
+		return empty, false
+	}
+
+	return value, true
+}
+
+// SessionMiddleware loads session by cookie and makes it available by [Session] function.
+//
+// Session is saved automatically before response is written (or after handler if it writes nothing),
+// only if it is modified or used (to prolong idle timeout). New empty sessions are not saved,
+// so anonymous requests do not create sessions. If handler returns without writing response
+// and session could not be saved, error response is returned.
+//
+// Session expires after idle timeout since last request or absolute timeout since creation
+func SessionMiddleware(cfg ...SessionConfig) echo.MiddlewareFunc {
+	config := newSessionConfig(cfg...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			session, err := loadSession(ctx, config)
+			if err != nil {
+				return Error(ctx, err)
+			}
+
+			Set(ctx, sessionKey, session)
+			ctx.Response().Before(func() {
+				// status is already chosen by handler, so failure could only be logged
+				if err := commitSession(ctx, config, session); err != nil {
+					log.Error().Ctx(Context(ctx)).Err(err).Msg("Save session")
+				}
+			})
+
+			err = next(ctx)
+			if ctx.Response().Committed {
+				return err
+			}
+
+			// handler did not write response (or returned error which is not written yet),
+			// so session is saved now and failure is returned instead of the response
+			if commitErr := commitSession(ctx, config, session); commitErr != nil {
+				return Error(ctx, commitErr)
+			}
+
+			return err
+		}
+	}
+}
+
+func loadSession(ctx echo.Context, config SessionConfig) (*SessionData, error) {
+	now := time.Now()
+	if cookie, err := ctx.Cookie(config.Cookie); err == nil && cookie.Value != "" {
+		record, ok, err := config.Store.Load(Context(ctx), cookie.Value)
+		if err != nil {
+			return nil, ErrSession.SetError(err)
+		}
+
+		expired := now.Sub(record.LastSeenAt) > config.IdleTimeout || now.Sub(record.CreatedAt) > config.AbsoluteTimeout
+		if ok && !expired {
+			if record.Values == nil {
+				record.Values = make(map[string]json.RawMessage)
+			}
+
+			return &SessionData{
+				record: record,
+				value:  cookie.Value,
+			}, nil
+		}
+
+		if ok {
+			if err = config.Store.Delete(Context(ctx), cookie.Value); err != nil {
+				return nil, ErrSession.SetError(err)
+			}
+		}
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionData{
+		record: SessionRecord{
+			ID:         id,
+			Values:     make(map[string]json.RawMessage),
+			CreatedAt:  now,
+			LastSeenAt: now,
+		},
+		isNew: true,
+	}, nil
+}
+
+// commitSession saves session once per request
+func commitSession(ctx echo.Context, config SessionConfig, session *SessionData) error {
+	session.mx.Lock()
+	defer session.mx.Unlock()
+
+	if session.committed {
+		return nil
+	}
+
+	if err := saveSession(ctx, config, session); err != nil {
+		if isError(err, ErrSessionTooLarge) {
+			return err
+		}
+
+		return ErrSession.SetError(err)
+	}
+
+	session.committed = true
+	return nil
+}
+
+// saveSession saves or removes session. Must be called under lock
+func saveSession(ctx echo.Context, config SessionConfig, session *SessionData) error {
+
+	if session.destroyed {
+		if !session.isNew {
+			if err := config.Store.Delete(Context(ctx), session.value); err != nil {
+				return err
+			}
+		}
+
+		if session.rotated != "" {
+			if err := config.Store.Delete(Context(ctx), session.rotated); err != nil {
+				return err
+			}
+		}
+
+		ctx.SetCookie(sessionCookie(config, "", time.Unix(0, 0)))
+		return nil
+	}
+
+	now := time.Now()
+	touched := now.Sub(session.record.LastSeenAt) >= min(sessionTouchInterval, config.IdleTimeout/2)
+	if !session.modified && (session.isNew || !touched) {
+		return nil
+	}
+
+	if session.rotated != "" {
+		if err := config.Store.Delete(Context(ctx), session.rotated); err != nil {
+			return err
+		}
+	}
+
+	session.record.LastSeenAt = now
+	expiresAt := session.record.CreatedAt.Add(config.AbsoluteTimeout)
+	ttl := min(config.IdleTimeout, expiresAt.Sub(now))
+
+	value, err := config.Store.Save(Context(ctx), session.record, ttl)
+	if err != nil {
+		return err
+	}
+
+	// server side stores keep cookie value, so cookie is set only if it is changed
+	if value != session.value || session.isNew {
+		ctx.SetCookie(sessionCookie(config, value, expiresAt))
+	}
+
+	session.value = value
+	session.isNew = false
+	session.modified = false
+	session.rotated = ""
+	return nil
+}
+
+func sessionCookie(config SessionConfig, value string, expiresAt time.Time) *http.Cookie {
+	options := CookieOptions{
+		Path:     config.Path,
+		Domain:   config.Domain,
+		SameSite: config.SameSite,
+		Insecure: config.Insecure,
+	}
+
+	cookie := options.cookie(config.Cookie, value, expiresAt)
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+
+	return cookie
+}
+
+func newSessionID() (string, error) {
+	id := make([]byte, sessionIDLength)
+	if _, err := rand.Read(id); err != nil {
+		return "", ErrSession.SetError(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
@@ -0,0 +1,70 @@
+package echox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// maxSessionCookieSize is max size of cookie value, browsers limit whole cookie to 4KB
+const maxSessionCookieSize = 4000
+
+// CookieSessionStore is [SessionStore] which keeps whole session in cookie encrypted by AES-GCM.
+// It does not need any server side storage, but session size is limited by 4KB and
+// sessions could not be revoked before expiration.
+type CookieSessionStore struct {
+	keyring *cookieKeyring
+}
+
+// NewCookieSessionStore creates [CookieSessionStore] with keys of at least 16 bytes.
+// Sessions are encrypted by the first key and decrypted by any of keys, so keys could be rotated:
+// new key is added first and old key is removed after absolute session timeout
+func NewCookieSessionStore(keys ...[]byte) (*CookieSessionStore, error) {
+	keyring, err := newCookieKeyring(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CookieSessionStore{
+		keyring: keyring,
+	}, nil
+}
+
+func (store *CookieSessionStore) Load(_ context.Context, value string) (SessionRecord, bool, error) {
+	blob, err := store.keyring.decrypt("", value)
+	if err != nil {
+		// tampered or encrypted by removed key
+		return SessionRecord{}, false, nil
+	}
+
+	var record SessionRecord
+	if err = json.Unmarshal(blob, &record); err != nil {
+		return SessionRecord{}, false, nil
+	}
+
+	return record, true, nil
+}
+
+func (store *CookieSessionStore) Save(_ context.Context, record SessionRecord, _ time.Duration) (string, error) {
+	blob, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	// session expiration is checked by SessionMiddleware
+	value, err := store.keyring.encrypt("", blob, time.Time{})
+	if err != nil {
+		return "", err
+	}
+
+	if len(value) > maxSessionCookieSize {
+		return "", ErrSessionTooLarge
+	}
+
+	return value, nil
+}
+
+// Delete does nothing: cookie is expired by [SessionMiddleware]
+func (store *CookieSessionStore) Delete(context.Context, string) error {
+	return nil
+}
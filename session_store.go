@@ -0,0 +1,223 @@
+package echox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const fileSessionExtension = ".session"
+
+// MemorySessionStore is in-memory [SessionStore]. It works only within one service instance.
+type MemorySessionStore struct {
+	sessions  map[string]memorySession
+	lastSweep time.Time
+	mx        sync.Mutex
+}
+
+type memorySession struct {
+	record    SessionRecord
+	expiresAt time.Time
+}
+
+// NewMemorySessionStore creates [MemorySessionStore]
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]memorySession),
+	}
+}
+
+func (store *MemorySessionStore) Load(_ context.Context, value string) (SessionRecord, bool, error) {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	session, ok := store.sessions[value]
+	if !ok || time.Now().After(session.expiresAt) {
+		return SessionRecord{}, false, nil
+	}
+
+	return session.record, true, nil
+}
+
+func (store *MemorySessionStore) Save(_ context.Context, record SessionRecord, ttl time.Duration) (string, error) {
+	// copy values, so record could not be changed by session after save
+	values := make(map[string]json.RawMessage, len(record.Values))
+	for key, value := range record.Values {
+		values[key] = value
+	}
+	record.Values = values
+
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	now := time.Now()
+	store.sweep(now)
+	store.sessions[record.ID] = memorySession{
+		record:    record,
+		expiresAt: now.Add(ttl),
+	}
+
+	return record.ID, nil
+}
+
+func (store *MemorySessionStore) Delete(_ context.Context, value string) error {
+	store.mx.Lock()
+	defer store.mx.Unlock()
+
+	delete(store.sessions, value)
+	return nil
+}
+
+// sweep removes expired sessions not often than once a minute
+func (store *MemorySessionStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < time.Minute {
+		return
+	}
+
+	store.lastSweep = now
+	for id, session := range store.sessions {
+		if now.After(session.expiresAt) {
+			delete(store.sessions, id)
+		}
+	}
+}
+
+// FileSessionStore is [SessionStore] which keeps sessions in files of local directory.
+//
+// Every session is file named by session ID hash. Expired files are removed on read
+// or by [FileSessionStore.DeleteExpired]
+type FileSessionStore struct {
+	dir string
+}
+
+type fileSession struct {
+	Record    SessionRecord `json:"record"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// NewFileSessionStore creates [FileSessionStore] and creates provided directory if it does not exist
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileSessionStore{
+		dir: dir,
+	}, nil
+}
+
+func (store *FileSessionStore) Load(_ context.Context, value string) (SessionRecord, bool, error) {
+	path := store.path(value)
+	session, err := readFileSession(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return SessionRecord{}, false, nil
+		}
+
+		return SessionRecord{}, false, err
+	}
+
+	if session.Record.ID != value {
+		return SessionRecord{}, false, nil
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		_ = os.Remove(path)
+		return SessionRecord{}, false, nil
+	}
+
+	return session.Record, true, nil
+}
+
+func (store *FileSessionStore) Save(_ context.Context, record SessionRecord, ttl time.Duration) (string, error) {
+	blob, err := json.Marshal(fileSession{
+		Record:    record,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	path := store.path(record.ID)
+
+	// write to temporary file and rename it, so readers never get partially written file
+	tmp, err := os.CreateTemp(store.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(blob); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return record.ID, nil
+}
+
+func (store *FileSessionStore) Delete(_ context.Context, value string) error {
+	if err := os.Remove(store.path(value)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpired removes all expired sessions and returns their count
+func (store *FileSessionStore) DeleteExpired(_ context.Context) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(store.dir, "*"+fileSessionExtension))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	removed := 0
+	for _, path := range paths {
+		session, err := readFileSession(path)
+		if err != nil || !now.After(session.ExpiresAt) {
+			// file could be removed concurrently
+			continue
+		}
+
+		if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (store *FileSessionStore) path(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return filepath.Join(store.dir, hex.EncodeToString(hash[:])+fileSessionExtension)
+}
+
+func readFileSession(path string) (fileSession, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return fileSession{}, err
+	}
+
+	var session fileSession
+	if err = json.Unmarshal(blob, &session); err != nil {
+		return fileSession{}, err
+	}
+
+	return session, nil
+}
@@ -0,0 +1,204 @@
+package echox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newSessionHandler(config SessionConfig) *echo.Echo {
+	handler := echo.New()
+	handler.Use(SessionMiddleware(config))
+	handler.POST("/login", func(ctx echo.Context) error {
+		if err := Session(ctx).Set("user", ctx.QueryParam("user")); err != nil {
+			return err
+		}
+
+		if err := Session(ctx).Rotate(); err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	})
+	handler.GET("/me", func(ctx echo.Context) error {
+		user, _ := SessionValue[string](ctx, "user")
+		return ctx.String(http.StatusOK, user)
+	})
+	handler.POST("/logout", func(ctx echo.Context) error {
+		Session(ctx).Destroy()
+		return ctx.NoContent(http.StatusNoContent)
+	})
+	handler.POST("/remember", func(ctx echo.Context) error {
+		// response is not written by handler
+		return Session(ctx).Set("note", ctx.QueryParam("note"))
+	})
+
+	return handler
+}
+
+func serveSession(handler http.Handler, method, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func responseCookie(recorder *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	fileStore, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookieStore, err := NewCookieSessionStore([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		store SessionStore
+		// revocable stores remove rotated & destroyed sessions
+		revocable bool
+	}{
+		{"memory", NewMemorySessionStore(), true},
+		{"file", fileStore, true},
+		{"cookie", cookieStore, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newSessionHandler(SessionConfig{Store: tt.store})
+			me := func(cookie *http.Cookie) string {
+				return serveSession(handler, http.MethodGet, "/me", cookie).Body.String()
+			}
+
+			// anonymous request does not create session
+			if cookie := responseCookie(serveSession(handler, http.MethodGet, "/me", nil), defaultSessionCookie); cookie != nil {
+				t.Fatalf("anonymous session cookie is set: %v", cookie)
+			}
+
+			first := responseCookie(serveSession(handler, http.MethodPost, "/login?user=alice", nil), defaultSessionCookie)
+			if first == nil || !first.HttpOnly || !first.Secure || first.SameSite != http.SameSiteLaxMode {
+				t.Fatalf("session cookie = %v", first)
+			}
+
+			if user := me(first); user != "alice" {
+				t.Fatalf("user = %q, want alice", user)
+			}
+
+			// login again rotates session ID
+			second := responseCookie(serveSession(handler, http.MethodPost, "/login?user=bob", first), defaultSessionCookie)
+			if second == nil || second.Value == first.Value {
+				t.Fatalf("session is not rotated: %v", second)
+			}
+
+			if user := me(second); user != "bob" {
+				t.Fatalf("user after rotation = %q, want bob", user)
+			}
+
+			if user := me(first); tt.revocable && user != "" {
+				t.Fatalf("session before rotation is valid: user = %q", user)
+			}
+
+			logout := serveSession(handler, http.MethodPost, "/logout", second)
+			if cookie := responseCookie(logout, defaultSessionCookie); cookie == nil || cookie.Value != "" || cookie.MaxAge >= 0 {
+				t.Fatalf("session cookie is not expired: %v", cookie)
+			}
+
+			if user := me(second); tt.revocable && user != "" {
+				t.Fatalf("destroyed session is valid: user = %q", user)
+			}
+		})
+	}
+}
+
+func TestSessionMiddlewareHandlerWithoutResponse(t *testing.T) {
+	cookieStore, err := NewCookieSessionStore([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := newSessionHandler(SessionConfig{Store: cookieStore})
+
+	// session is saved even if handler writes nothing
+	recorder := serveSession(handler, http.MethodPost, "/remember?note=short", nil)
+	cookie := responseCookie(recorder, defaultSessionCookie)
+	if recorder.Code != http.StatusOK || cookie == nil {
+		t.Fatalf("status = %d, cookie = %v", recorder.Code, cookie)
+	}
+
+	// session which could not be saved is not reported as success
+	recorder = serveSession(handler, http.MethodPost, "/remember?note="+strings.Repeat("x", maxSessionCookieSize), cookie)
+	if recorder.Code != http.StatusInternalServerError || !strings.Contains(recorder.Body.String(), "session_too_large") {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if cookie = responseCookie(recorder, defaultSessionCookie); cookie != nil {
+		t.Fatalf("session cookie is set: %v", cookie)
+	}
+}
+
+func TestSessionMiddlewareExpiration(t *testing.T) {
+	tests := []struct {
+		name   string
+		expire func(record *SessionRecord)
+	}{
+		{"idle timeout", func(record *SessionRecord) {
+			record.LastSeenAt = record.LastSeenAt.Add(-2 * time.Hour)
+		}},
+		{"absolute timeout", func(record *SessionRecord) {
+			record.CreatedAt = record.CreatedAt.Add(-48 * time.Hour)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemorySessionStore()
+			handler := newSessionHandler(SessionConfig{
+				Store:           store,
+				IdleTimeout:     time.Hour,
+				AbsoluteTimeout: 24 * time.Hour,
+			})
+
+			cookie := responseCookie(serveSession(handler, http.MethodPost, "/login?user=alice", nil), defaultSessionCookie)
+			if user := serveSession(handler, http.MethodGet, "/me", cookie).Body.String(); user != "alice" {
+				t.Fatalf("user = %q, want alice", user)
+			}
+
+			store.mx.Lock()
+			session := store.sessions[cookie.Value]
+			tt.expire(&session.record)
+			store.sessions[cookie.Value] = session
+			store.mx.Unlock()
+
+			if user := serveSession(handler, http.MethodGet, "/me", cookie).Body.String(); user != "" {
+				t.Fatalf("expired session is valid: user = %q", user)
+			}
+
+			store.mx.Lock()
+			_, ok := store.sessions[cookie.Value]
+			store.mx.Unlock()
+			if ok {
+				t.Fatal("expired session is not removed")
+			}
+		})
+	}
+}
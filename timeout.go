@@ -0,0 +1,217 @@
+package echox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/boostgo/errorx"
+	"github.com/boostgo/log"
+	"github.com/labstack/echo/v4"
+)
+
+// TimeoutConfig describes [TimeoutMiddleware].
+type TimeoutConfig struct {
+	// Routes are timeouts of routes by keys like "GET /users/:id" or "/users/:id" (any method).
+	// Zero or negative timeout disables timeout of the route
+	Routes map[string]time.Duration
+	// OnLate is called when handler finished after timeout response was sent.
+	// Late handlers are logged anyway
+	OnLate func(request *http.Request, elapsed time.Duration, err error)
+}
+
+func (config TimeoutConfig) timeout(ctx echo.Context, duration time.Duration) time.Duration {
+	method := ctx.Request().Method
+	for _, route := range []string{method + " " + ctx.Path(), ctx.Path()} {
+		if timeout, ok := config.Routes[route]; ok {
+			return timeout
+		}
+	}
+
+	return duration
+}
+
+// TimeoutMiddleware limits handler execution time.
+//
+// Handler runs with request context which is canceled on timeout. Handler output is buffered:
+// if handler finished in time, buffered response is sent, otherwise only timeout failure is sent
+// and all later handler writes are dropped. Errors returned by handler are returned as is
+// (to echo error handler), so they are not rendered twice.
+//
+// Handler gets its own echo context: request, path & params are copied, but values set by [echo.Context.Set]
+// before this middleware are not, so pass values by request context (see [Set]).
+//
+// Handlers which stream response (flush) must not be wrapped by this middleware
+func TimeoutMiddleware(duration time.Duration, cfg ...TimeoutConfig) echo.MiddlewareFunc {
+	var config TimeoutConfig
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			timeout := config.timeout(ctx, duration)
+			if timeout <= 0 {
+				return next(ctx)
+			}
+
+			native, cancel := context.WithTimeout(Context(ctx), timeout)
+			defer cancel()
+
+			writer := newTimeoutWriter(ctx.Response().Header())
+			handlerCtx := ctx.Echo().NewContext(ctx.Request().WithContext(native), writer)
+			handlerCtx.SetPath(ctx.Path())
+			handlerCtx.SetParamNames(ctx.ParamNames()...)
+			handlerCtx.SetParamValues(ctx.ParamValues()...)
+			handlerCtx.SetHandler(ctx.Handler())
+
+			start := time.Now()
+			done := make(chan timeoutResult, 1)
+			go func() {
+				// http.ErrAbortHandler is not recovered by callRecovered, so it is passed to the request goroutine
+				// where net/http handles it. Panic of this goroutine would crash the process
+				defer func() {
+					if value := recover(); value != nil {
+						done <- timeoutResult{abort: value}
+					}
+				}()
+
+				done <- timeoutResult{err: callRecovered(handlerCtx, next)}
+			}()
+
+			select {
+			case result := <-done:
+				if result.abort != nil {
+					writer.discard()
+					panic(result.abort)
+				}
+
+				// response of panicked handler could be incomplete
+				if errors.Is(result.err, errorx.ErrPanicRecover) {
+					writer.discard()
+					return result.err
+				}
+
+				writer.commit(ctx.Response())
+				return result.err
+			case <-native.Done():
+				writer.discard()
+				go reportLateHandler(handlerCtx.Request(), config, start, done)
+				return Error(ctx, newHandlerTimeoutError(timeout))
+			}
+		}
+	}
+}
+
+// timeoutResult is result of the handler called by [TimeoutMiddleware]
+type timeoutResult struct {
+	err error
+	// abort is panic value which must be re-panicked in the request goroutine
+	abort any
+}
+
+// reportLateHandler waits for the handler which did not finish in time
+func reportLateHandler(request *http.Request, config TimeoutConfig, start time.Time, done <-chan timeoutResult) {
+	result := <-done
+	elapsed := time.Since(start)
+
+	err := result.err
+	if result.abort != nil {
+		err = fmt.Errorf("handler aborted: %v", result.abort)
+	}
+
+	log.
+		Warn().
+		Ctx(request.Context()).
+		Err(err).
+		Str("method", request.Method).
+		Str("elapsed", elapsed.String()).
+		Msg("Handler finished after timeout: " + request.RequestURI)
+
+	if config.OnLate != nil {
+		config.OnLate(request, elapsed, err)
+	}
+}
+
+// timeoutWriter buffers handler response till the handler finishes.
+// After commit or discard all writes return [http.ErrHandlerTimeout]
+type timeoutWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+	closed bool
+	mx     sync.Mutex
+}
+
+func newTimeoutWriter(header http.Header) *timeoutWriter {
+	// keep headers set by previous middlewares
+	return &timeoutWriter{
+		header: header.Clone(),
+	}
+}
+
+func (writer *timeoutWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *timeoutWriter) WriteHeader(statusCode int) {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	if writer.closed || writer.status != 0 {
+		return
+	}
+
+	writer.status = statusCode
+}
+
+func (writer *timeoutWriter) Write(b []byte) (int, error) {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	if writer.closed {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+
+	return writer.body.Write(b)
+}
+
+// commit writes buffered response to provided response
+func (writer *timeoutWriter) commit(response *echo.Response) {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	writer.closed = true
+
+	header := response.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range writer.header {
+		header[key] = values
+	}
+
+	if writer.status == 0 {
+		return
+	}
+
+	response.WriteHeader(writer.status)
+	_, _ = response.Write(writer.body.Bytes())
+}
+
+// discard drops buffered response
+func (writer *timeoutWriter) discard() {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	writer.closed = true
+	writer.body.Reset()
+}
@@ -0,0 +1,85 @@
+package echox
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	late := make(chan error, 1)
+	config := TimeoutConfig{
+		Routes: map[string]time.Duration{"GET /fast": time.Second},
+		OnLate: func(_ *http.Request, _ time.Duration, err error) {
+			late <- err
+		},
+	}
+
+	handler := echo.New()
+	handler.GET("/fast", func(ctx echo.Context) error {
+		ctx.Response().Header().Set("X-Handler", "fast")
+		return ctx.String(http.StatusCreated, "done")
+	}, TimeoutMiddleware(10*time.Millisecond, config))
+	handler.GET("/slow", func(ctx echo.Context) error {
+		<-Context(ctx).Done()
+		time.Sleep(10 * time.Millisecond)
+
+		// write after timeout response is dropped
+		ctx.Response().Header().Set("X-Handler", "slow")
+		_, err := ctx.Response().Write([]byte("late"))
+		return err
+	}, TimeoutMiddleware(10*time.Millisecond, config))
+
+	fast := httptest.NewRecorder()
+	handler.ServeHTTP(fast, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if fast.Code != http.StatusCreated || fast.Body.String() != "done" || fast.Header().Get("X-Handler") != "fast" {
+		t.Fatalf("fast status = %d, body = %q", fast.Code, fast.Body)
+	}
+
+	slow := httptest.NewRecorder()
+	handler.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if slow.Code != http.StatusRequestTimeout {
+		t.Fatalf("slow status = %d: %s", slow.Code, slow.Body)
+	}
+
+	select {
+	case err := <-late:
+		if !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Fatalf("late write error = %v, want %v", err, http.ErrHandlerTimeout)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("late handler is not reported")
+	}
+
+	if slow.Header().Get("X-Handler") == "slow" || slow.Body.String() == "late" {
+		t.Fatalf("late write is sent: header = %q, body = %q", slow.Header().Get("X-Handler"), slow.Body)
+	}
+}
+
+func TestTimeoutMiddlewareAbortHandler(t *testing.T) {
+	handler := echo.New()
+	handler.GET("/abort", func(echo.Context) error {
+		panic(http.ErrAbortHandler)
+	}, TimeoutMiddleware(time.Second))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// net/http aborts the response, process must not crash
+	if response, err := http.Get(server.URL + "/abort"); err == nil {
+		_ = response.Body.Close()
+		t.Fatalf("expected aborted response, got status %d", response.StatusCode)
+	}
+
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler { //nolint:errorlint
+			t.Fatalf("panic = %v, want %v", value, http.ErrAbortHandler)
+		}
+	}()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+}
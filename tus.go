@@ -0,0 +1,610 @@
+package echox
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boostgo/convert"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	TusVersion = "1.0.0"
+
+	tusExtensions  = "creation,termination,expiration"
+	tusContentType = "application/offset+octet-stream"
+
+	tusResumableHeader = "Tus-Resumable"
+	tusVersionHeader   = "Tus-Version"
+	tusExtensionHeader = "Tus-Extension"
+	tusMaxSizeHeader   = "Tus-Max-Size"
+	uploadOffsetHeader = "Upload-Offset"
+	uploadLengthHeader = "Upload-Length"
+	uploadMetaHeader   = "Upload-Metadata"
+	uploadExpireHeader = "Upload-Expires"
+)
+
+var tusIDPattern = regexp.MustCompile(`^[a-f0-9]{32}$`)
+
+// TusUpload is state of one resumable upload.
+type TusUpload struct {
+	ID        string            `json:"id"`
+	Size      int64             `json:"size"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+	// Finished is true if completion hook succeeded after all upload bytes are received
+	Finished bool `json:"finished,omitempty"`
+}
+
+// Completed returns true if all upload bytes are received
+func (upload TusUpload) Completed() bool {
+	return upload.Offset >= upload.Size
+}
+
+// Expired returns true if upload is not finished and its expiration time passed
+func (upload TusUpload) Expired(now time.Time) bool {
+	return !upload.ExpiresAt.IsZero() && !upload.Finished && now.After(upload.ExpiresAt)
+}
+
+// TusStorage stores resumable uploads state and content.
+//
+// Get must return [ErrTusUploadNotFound] if upload does not exist
+type TusStorage interface {
+	Create(ctx context.Context, upload TusUpload) error
+	Get(ctx context.Context, id string) (TusUpload, error)
+	// Append writes content to the end of upload which must have provided offset and returns count of written bytes.
+	//
+	// Written bytes must be saved even if content reading failed
+	Append(ctx context.Context, id string, offset int64, content io.Reader) (int64, error)
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	// Finish marks upload as finished after completion hook succeeded
+	Finish(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// TusCompleteFunc is called when all upload bytes are received.
+// If it fails, it is called again on the next HEAD or PATCH request of the upload till it succeeds
+type TusCompleteFunc func(ctx echo.Context, upload TusUpload, file io.Reader) error
+
+// TusConfig describes tus protocol handler.
+type TusConfig struct {
+	// Storage is required uploads storage
+	Storage TusStorage
+	// MaxSize is maximum upload size in bytes. Zero means no limit
+	MaxSize int64
+	// Expiration is duration after which not completed upload expires. Zero means uploads never expire
+	Expiration time.Duration
+	// OnComplete is optional hook called after upload completion
+	OnComplete TusCompleteFunc
+}
+
+type tusHandler struct {
+	config TusConfig
+	active map[string]struct{}
+	mx     sync.Mutex
+}
+
+// Tus mounts tus 1.0 resumable uploads handler (creation, termination & expiration extensions) to provided path.
+//
+// Registers OPTIONS & POST methods for provided path and HEAD, PATCH & DELETE methods for "path/:id"
+func (g *RouterGroup) Tus(path string, cfg TusConfig, m ...echo.MiddlewareFunc) {
+	handler := &tusHandler{
+		config: cfg,
+		active: make(map[string]struct{}),
+	}
+
+	uploadPath := strings.TrimSuffix(path, "/") + "/:id"
+
+	g.OPTIONS(path, handler.options, m...)
+	g.POST(path, handler.tus(handler.create), m...)
+	g.HEAD(uploadPath, handler.tus(handler.head), m...)
+	g.PATCH(uploadPath, handler.tus(handler.patch), m...)
+	g.DELETE(uploadPath, handler.tus(handler.terminate), m...)
+}
+
+func (handler *tusHandler) options(ctx echo.Context) error {
+	header := ctx.Response().Header()
+	header.Set(tusResumableHeader, TusVersion)
+	header.Set(tusVersionHeader, TusVersion)
+	header.Set(tusExtensionHeader, tusExtensions)
+	if handler.config.MaxSize > 0 {
+		header.Set(tusMaxSizeHeader, convert.StringFromInt64(handler.config.MaxSize))
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// tus checks protocol version and sets common response headers
+func (handler *tusHandler) tus(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		ctx.Response().Header().Set(tusResumableHeader, TusVersion)
+		ctx.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+		if Header(ctx, tusResumableHeader).String() != TusVersion {
+			ctx.Response().Header().Set(tusVersionHeader, TusVersion)
+			return Error(ctx, ErrTusUnsupportedVersion)
+		}
+
+		return next(ctx)
+	}
+}
+
+func (handler *tusHandler) create(ctx echo.Context) error {
+	size, err := strconv.ParseInt(Header(ctx, uploadLengthHeader).String(), 10, 64)
+	if err != nil || size < 0 {
+		return Error(ctx, ErrTusInvalidLength)
+	}
+
+	if handler.config.MaxSize > 0 && size > handler.config.MaxSize {
+		return Error(ctx, newRequestBodyTooLargeError(handler.config.MaxSize))
+	}
+
+	metadata, err := parseTusMetadata(Header(ctx, uploadMetaHeader).String())
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	now := time.Now()
+	upload := TusUpload{
+		ID:        strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Size:      size,
+		Metadata:  metadata,
+		CreatedAt: now,
+	}
+	if handler.config.Expiration > 0 {
+		upload.ExpiresAt = now.Add(handler.config.Expiration)
+		ctx.Response().Header().Set(uploadExpireHeader, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+
+	if err = handler.config.Storage.Create(Context(ctx), upload); err != nil {
+		return Error(ctx, err)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderLocation, strings.TrimSuffix(ctx.Request().URL.Path, "/")+"/"+upload.ID)
+
+	// empty upload is completed right after creation
+	if upload.Completed() {
+		if !handler.lock(upload.ID) {
+			return Error(ctx, ErrTusUploadLocked)
+		}
+		defer handler.unlock(upload.ID)
+
+		if err = handler.complete(ctx, upload); err != nil {
+			return Error(ctx, err)
+		}
+	}
+
+	return ctx.NoContent(http.StatusCreated)
+}
+
+func (handler *tusHandler) head(ctx echo.Context) error {
+	upload, err := handler.upload(ctx)
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	// completion hook failed before: upload is not reported as received till hook succeeds
+	if upload.Completed() && !upload.Finished {
+		if !handler.lock(upload.ID) {
+			return Error(ctx, ErrTusUploadLocked)
+		}
+		defer handler.unlock(upload.ID)
+
+		if upload, err = handler.upload(ctx); err != nil {
+			return Error(ctx, err)
+		}
+
+		if upload.Completed() && !upload.Finished {
+			if err = handler.complete(ctx, upload); err != nil {
+				return Error(ctx, err)
+			}
+		}
+	}
+
+	header := ctx.Response().Header()
+	header.Set(uploadOffsetHeader, convert.StringFromInt64(upload.Offset))
+	header.Set(uploadLengthHeader, convert.StringFromInt64(upload.Size))
+	if len(upload.Metadata) > 0 {
+		header.Set(uploadMetaHeader, formatTusMetadata(upload.Metadata))
+	}
+	if !upload.ExpiresAt.IsZero() {
+		header.Set(uploadExpireHeader, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (handler *tusHandler) patch(ctx echo.Context) error {
+	if mediaType(Header(ctx, echo.HeaderContentType).String()) != tusContentType {
+		return Error(ctx, ErrTusInvalidContentType)
+	}
+
+	offset, err := strconv.ParseInt(Header(ctx, uploadOffsetHeader).String(), 10, 64)
+	if err != nil || offset < 0 {
+		return Error(ctx, ErrTusInvalidOffset)
+	}
+
+	id := ctx.Param("id")
+	if !tusIDPattern.MatchString(id) {
+		return Error(ctx, ErrTusUploadNotFound)
+	}
+
+	// only one PATCH request per upload could be processed at the same time
+	if !handler.lock(id) {
+		return Error(ctx, ErrTusUploadLocked)
+	}
+	defer handler.unlock(id)
+
+	upload, err := handler.upload(ctx)
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	if offset != upload.Offset {
+		return Error(ctx, newTusOffsetMismatchError(upload.Offset, offset))
+	}
+
+	// body longer than the rest of upload is rejected as tus requires
+	remaining := upload.Size - upload.Offset
+	if ctx.Request().ContentLength > remaining {
+		return Error(ctx, newRequestBodyTooLargeError(remaining))
+	}
+
+	content := &tusContentReader{body: ctx.Request().Body, limit: remaining, remaining: remaining}
+	written, err := handler.config.Storage.Append(Context(ctx), upload.ID, upload.Offset, content)
+	upload.Offset += written
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	ctx.Response().Header().Set(uploadOffsetHeader, convert.StringFromInt64(upload.Offset))
+	if !upload.ExpiresAt.IsZero() {
+		ctx.Response().Header().Set(uploadExpireHeader, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+
+	if upload.Completed() && !upload.Finished {
+		if err = handler.complete(ctx, upload); err != nil {
+			return Error(ctx, err)
+		}
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (handler *tusHandler) terminate(ctx echo.Context) error {
+	upload, err := handler.upload(ctx)
+	if err != nil {
+		return Error(ctx, err)
+	}
+
+	// upload could not be deleted while PATCH request writes it
+	if !handler.lock(upload.ID) {
+		return Error(ctx, ErrTusUploadLocked)
+	}
+	defer handler.unlock(upload.ID)
+
+	if err = handler.config.Storage.Delete(Context(ctx), upload.ID); err != nil {
+		return Error(ctx, err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// tusContentReader reads PATCH body till the end of upload and fails if body is longer.
+// The last upload byte is returned only after the body end is reached, so longer body never completes upload
+type tusContentReader struct {
+	body      io.Reader
+	limit     int64
+	remaining int64
+}
+
+func (reader *tusContentReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if reader.remaining > 1 {
+		if int64(len(p)) > reader.remaining-1 {
+			p = p[:reader.remaining-1]
+		}
+
+		n, err := reader.body.Read(p)
+		reader.remaining -= int64(n)
+		return n, err
+	}
+
+	var tail [2]byte
+	n, err := io.ReadFull(reader.body, tail[:reader.remaining+1])
+	if int64(n) > reader.remaining {
+		return 0, newRequestBodyTooLargeError(reader.limit)
+	}
+
+	copy(p, tail[:n])
+	reader.remaining -= int64(n)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// upload returns upload by path id and removes it if it is expired
+func (handler *tusHandler) upload(ctx echo.Context) (TusUpload, error) {
+	id := ctx.Param("id")
+	if !tusIDPattern.MatchString(id) {
+		return TusUpload{}, ErrTusUploadNotFound
+	}
+
+	upload, err := handler.config.Storage.Get(Context(ctx), id)
+	if err != nil {
+		return TusUpload{}, err
+	}
+
+	if upload.Expired(time.Now()) {
+		_ = handler.config.Storage.Delete(Context(ctx), id)
+		return TusUpload{}, ErrTusUploadExpired
+	}
+
+	return upload, nil
+}
+
+// lock marks upload as processed by request. Returns false if upload is already processed by another request
+func (handler *tusHandler) lock(id string) bool {
+	handler.mx.Lock()
+	defer handler.mx.Unlock()
+
+	if _, ok := handler.active[id]; ok {
+		return false
+	}
+
+	handler.active[id] = struct{}{}
+	return true
+}
+
+func (handler *tusHandler) unlock(id string) {
+	handler.mx.Lock()
+	defer handler.mx.Unlock()
+
+	delete(handler.active, id)
+}
+
+// complete calls completion hook and marks upload as finished only if hook succeeded, so failed hook is retried
+func (handler *tusHandler) complete(ctx echo.Context, upload TusUpload) error {
+	if handler.config.OnComplete != nil {
+		file, err := handler.config.Storage.Open(Context(ctx), upload.ID)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err = handler.config.OnComplete(ctx, upload, file); err != nil {
+			return err
+		}
+	}
+
+	return handler.config.Storage.Finish(Context(ctx), upload.ID)
+}
+
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, ErrTusInvalidMetadata
+		}
+
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, wrapError(ErrTusInvalidMetadata, err)
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+func formatTusMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		if value == "" {
+			pairs = append(pairs, key)
+			continue
+		}
+
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// LocalTusStorage is [TusStorage] implementation which keeps uploads in local directory.
+//
+// Every upload has 2 files: "<id>.bin" with content and "<id>.json" with upload state
+type LocalTusStorage struct {
+	dir string
+	mx  sync.Mutex
+}
+
+// NewLocalTusStorage creates [LocalTusStorage] and creates provided directory if it does not exist
+func NewLocalTusStorage(dir string) (*LocalTusStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &LocalTusStorage{
+		dir: dir,
+	}, nil
+}
+
+func (storage *LocalTusStorage) Create(_ context.Context, upload TusUpload) error {
+	file, err := os.OpenFile(storage.contentPath(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	storage.mx.Lock()
+	defer storage.mx.Unlock()
+
+	return storage.save(upload)
+}
+
+func (storage *LocalTusStorage) Get(_ context.Context, id string) (TusUpload, error) {
+	storage.mx.Lock()
+	defer storage.mx.Unlock()
+
+	return storage.load(id)
+}
+
+func (storage *LocalTusStorage) Append(_ context.Context, id string, offset int64, content io.Reader) (int64, error) {
+	file, err := os.OpenFile(storage.contentPath(id), os.O_WRONLY, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, ErrTusUploadNotFound
+		}
+
+		return 0, err
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	written, copyErr := io.Copy(file, content)
+
+	storage.mx.Lock()
+	defer storage.mx.Unlock()
+
+	upload, err := storage.load(id)
+	if err != nil {
+		return written, err
+	}
+
+	upload.Offset = offset + written
+	if err = storage.save(upload); err != nil {
+		return written, err
+	}
+
+	return written, copyErr
+}
+
+func (storage *LocalTusStorage) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	return os.Open(storage.contentPath(id))
+}
+
+func (storage *LocalTusStorage) Finish(_ context.Context, id string) error {
+	storage.mx.Lock()
+	defer storage.mx.Unlock()
+
+	upload, err := storage.load(id)
+	if err != nil {
+		return err
+	}
+
+	upload.Finished = true
+	return storage.save(upload)
+}
+
+func (storage *LocalTusStorage) Delete(_ context.Context, id string) error {
+	storage.mx.Lock()
+	defer storage.mx.Unlock()
+
+	if err := os.Remove(storage.contentPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Remove(storage.infoPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpired removes all expired uploads and returns their count
+func (storage *LocalTusStorage) DeleteExpired(ctx context.Context) (int, error) {
+	infos, err := filepath.Glob(filepath.Join(storage.dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	deleted := 0
+	for _, info := range infos {
+		upload, err := storage.Get(ctx, strings.TrimSuffix(filepath.Base(info), ".json"))
+		if err != nil || !upload.Expired(now) {
+			continue
+		}
+
+		if err = storage.Delete(ctx, upload.ID); err != nil {
+			return deleted, err
+		}
+
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (storage *LocalTusStorage) load(id string) (TusUpload, error) {
+	blob, err := os.ReadFile(storage.infoPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return TusUpload{}, ErrTusUploadNotFound
+		}
+
+		return TusUpload{}, err
+	}
+
+	var upload TusUpload
+	if err = json.Unmarshal(blob, &upload); err != nil {
+		return TusUpload{}, err
+	}
+
+	return upload, nil
+}
+
+// save writes upload state to temporary file and renames it, so state file is always consistent
+func (storage *LocalTusStorage) save(upload TusUpload) error {
+	blob, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	tmp := storage.infoPath(upload.ID) + ".tmp"
+	if err = os.WriteFile(tmp, blob, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, storage.infoPath(upload.ID))
+}
+
+func (storage *LocalTusStorage) contentPath(id string) string {
+	return filepath.Join(storage.dir, filepath.Base(id)+".bin")
+}
+
+func (storage *LocalTusStorage) infoPath(id string) string {
+	return filepath.Join(storage.dir, filepath.Base(id)+".json")
+}
@@ -0,0 +1,142 @@
+package echox
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// tusTestServer serves tus handler like [RouterGroup.Tus] does
+type tusTestServer struct {
+	handler  *tusHandler
+	echo     *echo.Echo
+	finished []string
+}
+
+func newTusTestServer(t *testing.T) *tusTestServer {
+	t.Helper()
+
+	storage, err := NewLocalTusStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &tusTestServer{echo: echo.New()}
+	server.handler = &tusHandler{
+		config: TusConfig{
+			Storage: storage,
+			OnComplete: func(_ echo.Context, _ TusUpload, file io.Reader) error {
+				content, err := io.ReadAll(file)
+				server.finished = append(server.finished, string(content))
+				return err
+			},
+		},
+		active: make(map[string]struct{}),
+	}
+
+	server.echo.POST("/files", server.handler.tus(server.handler.create))
+	server.echo.HEAD("/files/:id", server.handler.tus(server.handler.head))
+	server.echo.PATCH("/files/:id", server.handler.tus(server.handler.patch))
+	server.echo.DELETE("/files/:id", server.handler.tus(server.handler.terminate))
+	return server
+}
+
+func (server *tusTestServer) do(method, path string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, body)
+	request.Header.Set(tusResumableHeader, TusVersion)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.echo.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (server *tusTestServer) create(t *testing.T, size string) string {
+	t.Helper()
+
+	recorder := server.do(http.MethodPost, "/files", nil, map[string]string{uploadLengthHeader: size})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	return recorder.Header().Get(echo.HeaderLocation)
+}
+
+func (server *tusTestServer) patch(location, offset string, body io.Reader) *httptest.ResponseRecorder {
+	return server.do(http.MethodPatch, location, body, map[string]string{
+		echo.HeaderContentType: tusContentType,
+		uploadOffsetHeader:     offset,
+	})
+}
+
+func TestTusPatch(t *testing.T) {
+	server := newTusTestServer(t)
+	location := server.create(t, "11")
+
+	if recorder := server.patch(location, "0", strings.NewReader("hello ")); recorder.Code != http.StatusNoContent {
+		t.Fatalf("first patch status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	recorder := server.patch(location, "6", strings.NewReader("world"))
+	if recorder.Code != http.StatusNoContent || recorder.Header().Get(uploadOffsetHeader) != "11" {
+		t.Fatalf("second patch status = %d, offset = %s", recorder.Code, recorder.Header().Get(uploadOffsetHeader))
+	}
+
+	if len(server.finished) != 1 || server.finished[0] != "hello world" {
+		t.Fatalf("finished = %q", server.finished)
+	}
+}
+
+func TestTusPatchTooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		body func() io.Reader
+	}{
+		{"content length", func() io.Reader { return strings.NewReader("hello world") }},
+		// reader without length is sent chunked
+		{"chunked", func() io.Reader { return io.MultiReader(strings.NewReader("hello world")) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTusTestServer(t)
+			location := server.create(t, "5")
+
+			if recorder := server.patch(location, "0", tt.body()); recorder.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusRequestEntityTooLarge, recorder.Body)
+			}
+
+			recorder := server.do(http.MethodHead, location, nil, nil)
+			if recorder.Header().Get(uploadOffsetHeader) == "5" || len(server.finished) != 0 {
+				t.Fatalf("upload completed by oversize body: offset = %s", recorder.Header().Get(uploadOffsetHeader))
+			}
+		})
+	}
+}
+
+func TestTusTerminateLocked(t *testing.T) {
+	server := newTusTestServer(t)
+	location := server.create(t, "5")
+	id := strings.TrimPrefix(location, "/files/")
+
+	// PATCH request of the upload is in progress
+	server.handler.lock(id)
+	if recorder := server.do(http.MethodDelete, location, nil, nil); recorder.Code != http.StatusLocked {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusLocked)
+	}
+
+	server.handler.unlock(id)
+	if recorder := server.do(http.MethodDelete, location, nil, nil); recorder.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+
+	if recorder := server.do(http.MethodHead, location, nil, nil); recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
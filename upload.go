@@ -0,0 +1,407 @@
+package echox
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/boostgo/httpx"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	sniffLength = 512
+
+	defaultArchiveMaxEntries       = 10_000
+	defaultArchiveMaxSize          = 1 << 30 // 1GB
+	defaultArchiveMaxCompressRatio = 100
+)
+
+// knownExtensions contains extensions of content types which are missing or incomplete in [mime] package
+var knownExtensions = map[string][]string{
+	httpx.ContentTypeText:       {".txt", ".text", ".log", ".md", ".csv"},
+	httpx.ContentTypeCSV:        {".csv"},
+	httpx.ContentTypeHTML:       {".html", ".htm"},
+	httpx.ContentTypeJpeg:       {".jpg", ".jpeg", ".jpe"},
+	httpx.ContentTypePng:        {".png"},
+	httpx.ContentTypeGif:        {".gif"},
+	httpx.ContentTypeBmp:        {".bmp"},
+	httpx.ContentTypeIco:        {".ico"},
+	httpx.ContentTypeSvg:        {".svg"},
+	"image/webp":                {".webp"},
+	httpx.ContentTypePdf:        {".pdf"},
+	httpx.ContentTypeZip:        {".zip"},
+	httpx.ContentTypeRar:        {".rar"},
+	httpx.ContentTypeSevenZip:   {".7z"},
+	httpx.ContentTypeTar:        {".tar"},
+	"application/x-gzip":        {".gz", ".tgz"},
+	httpx.ContentTypeWord:       {".docx"},
+	httpx.ContentTypeExcel:      {".xlsx"},
+	httpx.ContentTypePowerPoint: {".pptx"},
+	httpx.ContentTypeEpub:       {".epub"},
+	httpx.ContentTypeJar:        {".jar"},
+	httpx.ContentTypeMp3:        {".mp3"},
+	httpx.ContentTypeWav:        {".wav"},
+	httpx.ContentTypeMp4:        {".mp4", ".m4v"},
+	httpx.ContentTypeMpeg:       {".mpeg", ".mpg"},
+	"video/webm":                {".webm"},
+}
+
+// contentTypeAliases are content types returned by [http.DetectContentType] which differ from
+// registered ones, so allow-lists and extension checks could use httpx content types
+var contentTypeAliases = map[string]string{
+	"image/x-icon": httpx.ContentTypeIco,
+	"audio/wave":   httpx.ContentTypeWav,
+	"audio/x-wav":  httpx.ContentTypeWav,
+}
+
+// imageConfigDecoders are decoders of image dimensions by content type. Decoders are called directly
+// instead of registering them in [image] package, so importers of the package do not get them implicitly
+var imageConfigDecoders = map[string]func(io.Reader) (image.Config, error){
+	httpx.ContentTypeGif:  gif.DecodeConfig,
+	httpx.ContentTypeJpeg: jpeg.DecodeConfig,
+	httpx.ContentTypePng:  png.DecodeConfig,
+}
+
+// UploadRules describes which uploaded files are accepted.
+//
+// Zero value accepts any file
+type UploadRules struct {
+	// AllowedTypes is list of allowed content types sniffed from file content.
+	// Supports wildcards like "image/*". Empty list allows any type
+	AllowedTypes []string
+	// AllowedExtensions is list of allowed file name extensions like ".png". Empty list allows any extension
+	AllowedExtensions []string
+	// MatchExtension requires file name extension to correspond sniffed content type
+	MatchExtension bool
+
+	// MinWidth, MinHeight, MaxWidth & MaxHeight limit image dimensions in pixels (zero means no limit).
+	// Dimensions are checked for gif, jpeg and png images only, other formats (webp, bmp, svg, etc.) are not checked
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+
+	// RejectArchiveBombs enables checking of zip based archives (zip, docx, xlsx, etc.) for zip bomb characteristics
+	RejectArchiveBombs bool
+	// MaxArchiveEntries is maximum count of archive entries. Default is 10000
+	MaxArchiveEntries int
+	// MaxArchiveSize is maximum uncompressed archive size in bytes. Default is 1GB
+	MaxArchiveSize int64
+	// MaxCompressionRatio is maximum ratio of uncompressed to compressed archive size. Default is 100
+	MaxCompressionRatio float64
+}
+
+func (rules UploadRules) withDefaults() UploadRules {
+	if rules.MaxArchiveEntries <= 0 {
+		rules.MaxArchiveEntries = defaultArchiveMaxEntries
+	}
+
+	if rules.MaxArchiveSize <= 0 {
+		rules.MaxArchiveSize = defaultArchiveMaxSize
+	}
+
+	if rules.MaxCompressionRatio <= 0 {
+		rules.MaxCompressionRatio = defaultArchiveMaxCompressRatio
+	}
+
+	return rules
+}
+
+func (rules UploadRules) checkDimensions() bool {
+	return rules.MinWidth > 0 || rules.MinHeight > 0 || rules.MaxWidth > 0 || rules.MaxHeight > 0
+}
+
+// ValidateUpload checks uploaded file by provided rules.
+//
+// Returns [ErrUploadTypeNotAllowed] (415 status) if content type or extension is not allowed and
+// [ErrUploadInvalid] (422 status) if file content does not satisfy rules
+func ValidateUpload(file *FormFile, rules UploadRules) error {
+	rules = rules.withDefaults()
+	contentType := canonicalContentType(file.DetectedContentType)
+	extension := strings.ToLower(filepath.Ext(file.Filename))
+
+	if len(rules.AllowedTypes) > 0 && !contentTypeAllowed(contentType, rules.AllowedTypes) {
+		return newUploadError(ErrUploadTypeNotAllowed, file, "content type is not allowed", rules.AllowedTypes)
+	}
+
+	if len(rules.AllowedExtensions) > 0 && !extensionAllowed(extension, rules.AllowedExtensions) {
+		return newUploadError(ErrUploadTypeNotAllowed, file, "extension is not allowed", rules.AllowedExtensions)
+	}
+
+	if rules.MatchExtension && !extensionMatches(contentType, extension) {
+		return newUploadError(ErrUploadInvalid, file, "extension does not match content type", nil)
+	}
+
+	if !rules.checkDimensions() && !rules.RejectArchiveBombs {
+		return nil
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	if rules.checkDimensions() && strings.HasPrefix(contentType, "image/") {
+		if reason := checkImageDimensions(content, contentType, rules); reason != "" {
+			return newUploadError(ErrUploadInvalid, file, reason, nil)
+		}
+	}
+
+	if rules.RejectArchiveBombs && isZipBased(contentType) {
+		if reason := checkArchive(content, file.Size, rules); reason != "" {
+			return newUploadError(ErrUploadInvalid, file, reason, nil)
+		}
+	}
+
+	return nil
+}
+
+// UploadValidationMiddleware validates all uploaded files of provided form fields (or all files if no fields provided)
+// by provided rules and returns failure response if any file is not valid.
+//
+// Use it as route middleware to set allow-lists per route
+func UploadValidationMiddleware(rules UploadRules, fields ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			form, err := ctx.MultipartForm()
+			if err != nil {
+				return Error(ctx, wrapError(httpx.ErrReadFormFile, err))
+			}
+
+			for field, headers := range form.File {
+				if len(fields) > 0 && !slices.Contains(fields, field) {
+					continue
+				}
+
+				for _, header := range headers {
+					file, err := newFormFile(field, header)
+					if err != nil {
+						return Error(ctx, err)
+					}
+
+					if err = ValidateUpload(file, rules); err != nil {
+						return Error(ctx, err)
+					}
+				}
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// sniffContentType detects content type by content magic bytes.
+//
+// Refines zip based formats (docx, xlsx, pptx, epub, jar) and some formats unknown for [http.DetectContentType]
+func sniffContentType(content io.ReaderAt, size int64) (string, error) {
+	head := make([]byte, sniffLength)
+	n, err := content.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("7z\xbc\xaf\x27\x1c")):
+		return httpx.ContentTypeSevenZip, nil
+	case bytes.HasPrefix(head, []byte("Rar!\x1a\x07")):
+		return httpx.ContentTypeRar, nil
+	case len(head) > 262 && bytes.Equal(head[257:262], []byte("ustar")):
+		return httpx.ContentTypeTar, nil
+	}
+
+	detected := http.DetectContentType(head)
+	switch mediaType(detected) {
+	case httpx.ContentTypeZip:
+		// epub must start with uncompressed "mimetype" entry
+		if bytes.Contains(head, []byte("mimetype"+httpx.ContentTypeEpub)) {
+			return httpx.ContentTypeEpub, nil
+		}
+
+		return zipContentType(content, size), nil
+	case "text/xml", "text/plain":
+		if bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+			return httpx.ContentTypeSvg, nil
+		}
+	}
+
+	if alias, ok := contentTypeAliases[mediaType(detected)]; ok {
+		return alias, nil
+	}
+
+	return detected, nil
+}
+
+// zipContentType detects zip based formats by archive entries
+func zipContentType(content io.ReaderAt, size int64) string {
+	archive, err := zip.NewReader(content, size)
+	if err != nil {
+		return httpx.ContentTypeZip
+	}
+
+	for _, entry := range archive.File {
+		switch {
+		case strings.HasPrefix(entry.Name, "word/"):
+			return httpx.ContentTypeWord
+		case strings.HasPrefix(entry.Name, "xl/"):
+			return httpx.ContentTypeExcel
+		case strings.HasPrefix(entry.Name, "ppt/"):
+			return httpx.ContentTypePowerPoint
+		case entry.Name == "META-INF/MANIFEST.MF":
+			return httpx.ContentTypeJar
+		}
+	}
+
+	return httpx.ContentTypeZip
+}
+
+func isZipBased(contentType string) bool {
+	switch contentType {
+	case httpx.ContentTypeZip, httpx.ContentTypeWord, httpx.ContentTypeExcel,
+		httpx.ContentTypePowerPoint, httpx.ContentTypeEpub, httpx.ContentTypeJar:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkImageDimensions checks dimensions of image with provided content type.
+// Images of formats without decoder are skipped
+func checkImageDimensions(content io.Reader, contentType string, rules UploadRules) string {
+	decodeConfig, ok := imageConfigDecoders[contentType]
+	if !ok {
+		return ""
+	}
+
+	config, err := decodeConfig(content)
+	if err != nil {
+		return "image could not be decoded"
+	}
+
+	switch {
+	case rules.MinWidth > 0 && config.Width < rules.MinWidth:
+		return "image width is less than " + strconv.Itoa(rules.MinWidth)
+	case rules.MinHeight > 0 && config.Height < rules.MinHeight:
+		return "image height is less than " + strconv.Itoa(rules.MinHeight)
+	case rules.MaxWidth > 0 && config.Width > rules.MaxWidth:
+		return "image width is greater than " + strconv.Itoa(rules.MaxWidth)
+	case rules.MaxHeight > 0 && config.Height > rules.MaxHeight:
+		return "image height is greater than " + strconv.Itoa(rules.MaxHeight)
+	default:
+		return ""
+	}
+}
+
+// checkArchive decompresses archive (with limits) to check real uncompressed size, not declared in headers
+func checkArchive(content io.ReaderAt, size int64, rules UploadRules) string {
+	archive, err := zip.NewReader(content, size)
+	if err != nil {
+		return "archive could not be read"
+	}
+
+	if len(archive.File) > rules.MaxArchiveEntries {
+		return "archive contains more than " + strconv.Itoa(rules.MaxArchiveEntries) + " entries"
+	}
+
+	maxSize := rules.MaxArchiveSize
+	if ratioSize := int64(float64(size) * rules.MaxCompressionRatio); ratioSize < maxSize {
+		maxSize = ratioSize
+	}
+
+	var total int64
+	for _, entry := range archive.File {
+		if strings.EqualFold(filepath.Ext(entry.Name), ".zip") {
+			return "archive contains nested archives"
+		}
+
+		reader, err := entry.Open()
+		if err != nil {
+			return "archive entry could not be read"
+		}
+
+		written, err := io.Copy(io.Discard, io.LimitReader(reader, maxSize-total+1))
+		_ = reader.Close()
+		if err != nil {
+			return "archive entry could not be read"
+		}
+
+		total += written
+		if total > maxSize {
+			return "archive uncompressed size or compression ratio is too big"
+		}
+	}
+
+	return ""
+}
+
+func contentTypeAllowed(contentType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = canonicalContentType(pattern)
+		if pattern == "*/*" || pattern == contentType {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func extensionAllowed(extension string, allowed []string) bool {
+	for _, allowedExtension := range allowed {
+		allowedExtension = strings.ToLower(allowedExtension)
+		if !strings.HasPrefix(allowedExtension, ".") {
+			allowedExtension = "." + allowedExtension
+		}
+
+		if allowedExtension == extension {
+			return true
+		}
+	}
+
+	return false
+}
+
+func extensionMatches(contentType, extension string) bool {
+	if slices.Contains(knownExtensions[contentType], extension) {
+		return true
+	}
+
+	extensions, _ := mime.ExtensionsByType(contentType)
+	return slices.Contains(extensions, extension)
+}
+
+// mediaType returns content type without parameters
+func mediaType(contentType string) string {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	return parsed
+}
+
+// canonicalContentType returns content type without parameters with aliases replaced by registered content types
+func canonicalContentType(contentType string) string {
+	contentType = mediaType(contentType)
+	if alias, ok := contentTypeAliases[contentType]; ok {
+		return alias
+	}
+
+	return contentType
+}
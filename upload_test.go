@@ -0,0 +1,63 @@
+package echox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/boostgo/httpx"
+	"github.com/labstack/echo/v4"
+)
+
+func TestValidateUploadExtension(t *testing.T) {
+	tests := []struct {
+		filename    string
+		content     string
+		contentType string
+		valid       bool
+	}{
+		{"favicon.ico", "\x00\x00\x01\x00\x01\x00\x10\x10", httpx.ContentTypeIco, true},
+		{"sound.wav", "RIFF\x24\x00\x00\x00WAVEfmt ", httpx.ContentTypeWav, true},
+		{"image.png", "\x89PNG\r\n\x1a\n0000", httpx.ContentTypePng, true},
+		{"image.jpg", "\x89PNG\r\n\x1a\n0000", httpx.ContentTypePng, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			ctx := newMultipartContext(t, nil, map[string]map[string]string{"file": {tt.filename: tt.content}})
+			files, err := Files(ctx, "file")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if files[0].DetectedContentType != tt.contentType {
+				t.Fatalf("detected content type = %q, want %q", files[0].DetectedContentType, tt.contentType)
+			}
+
+			err = ValidateUpload(files[0], UploadRules{
+				AllowedTypes:   []string{tt.contentType},
+				MatchExtension: true,
+			})
+			if (err == nil) != tt.valid {
+				t.Fatalf("valid = %t: %v", err == nil, err)
+			}
+		})
+	}
+}
+
+func TestUploadValidationMiddlewareNotMultipart(t *testing.T) {
+	handler := echo.New()
+	handler.POST("/", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}, UploadValidationMiddleware(UploadRules{AllowedTypes: []string{"image/*"}}))
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body)
+	}
+}
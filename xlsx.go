@@ -0,0 +1,301 @@
+package echox
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	xlsxFirstCustomFormat = 164
+	xlsxDateTimeFormat    = 22 // built-in "m/d/yy h:mm" format
+
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" ` +
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+		`Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" ` +
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" ` +
+		`Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" ` +
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" ` +
+		`Target="styles.xml"/>` +
+		`</Relationships>`
+
+	xlsxMainNamespace = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelsNamespace = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+)
+
+var (
+	xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+	floatFormatPattern = regexp.MustCompile(`^%\.(\d+)f$`)
+
+	// layoutReplacer converts Go time layout to Excel number format
+	layoutReplacer = strings.NewReplacer(
+		"2006", "yyyy", "January", "mmmm", "Monday", "dddd",
+		"Jan", "mmm", "Mon", "ddd", "06", "yy", "01", "mm", "02", "dd",
+		"15", "hh", "04", "mm", "05", "ss", "PM", "AM/PM",
+	)
+)
+
+// xlsxWriter streams minimal XLSX document with one sheet: rows are written right to the zip entry
+type xlsxWriter struct {
+	archive   *zip.Writer
+	sheet     *bufio.Writer
+	sheetName string
+	columns   []exportColumn
+	styles    []int    // style index of every column
+	formats   []string // custom number formats
+	row       int
+}
+
+func newXLSXWriter(target io.Writer, sheetName string, columns []exportColumn) *xlsxWriter {
+	writer := &xlsxWriter{
+		archive:   zip.NewWriter(target),
+		sheetName: sheetName,
+		columns:   columns,
+		styles:    make([]int, len(columns)),
+		formats:   make([]string, 0),
+	}
+
+	// style 0 is default, style 1 is built-in date time, next styles are custom formats
+	for i, column := range columns {
+		switch {
+		case column.kind == exportTime && column.format == "":
+			writer.styles[i] = 1
+		case column.kind == exportTime:
+			writer.styles[i] = writer.addFormat(layoutReplacer.Replace(column.format))
+		case column.kind == exportNumber && floatFormatPattern.MatchString(column.format):
+			digits, _ := strconv.Atoi(floatFormatPattern.FindStringSubmatch(column.format)[1])
+			numberFormat := "0"
+			if digits > 0 {
+				numberFormat += "." + strings.Repeat("0", digits)
+			}
+
+			writer.styles[i] = writer.addFormat(numberFormat)
+		}
+	}
+
+	return writer
+}
+
+func (writer *xlsxWriter) addFormat(format string) int {
+	writer.formats = append(writer.formats, format)
+	return len(writer.formats) + 1
+}
+
+func (writer *xlsxWriter) writeHeader() error {
+	entry, err := writer.archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+
+	writer.sheet = bufio.NewWriter(entry)
+	_, _ = writer.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	_, _ = writer.sheet.WriteString(`<worksheet xmlns="` + xlsxMainNamespace + `"><sheetData>`)
+
+	writer.row++
+	_, _ = writer.sheet.WriteString(`<row r="1">`)
+	for i, column := range writer.columns {
+		writer.writeString(i, column.header)
+	}
+	_, err = writer.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (writer *xlsxWriter) writeRow(value reflect.Value) error {
+	writer.row++
+	_, _ = writer.sheet.WriteString(`<row r="` + strconv.Itoa(writer.row) + `">`)
+
+	if value.IsValid() {
+		for i, column := range writer.columns {
+			writer.writeCell(i, fieldValue(value, column.index), column)
+		}
+	}
+
+	_, err := writer.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (writer *xlsxWriter) writeCell(i int, value reflect.Value, column exportColumn) {
+	if !value.IsValid() {
+		return
+	}
+
+	switch column.kind {
+	case exportTime:
+		moment := value.Interface().(time.Time)
+		if moment.IsZero() {
+			return
+		}
+
+		writer.writeNumber(i, strconv.FormatFloat(excelSerial(moment), 'f', -1, 64))
+	case exportNumber:
+		var number float64
+		switch {
+		case value.CanInt():
+			number = float64(value.Int())
+		case value.CanUint():
+			number = float64(value.Uint())
+		default:
+			number = value.Float()
+		}
+
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return
+		}
+
+		writer.writeNumber(i, strconv.FormatFloat(number, 'f', -1, 64))
+	case exportBool:
+		_, _ = writer.sheet.WriteString(`<c r="` + cellReference(i, writer.row) + `" t="b"><v>` +
+			strconv.Itoa(boolToInt(value.Bool())) + `</v></c>`)
+	default:
+		writer.writeString(i, exportText(value, column.format))
+	}
+}
+
+func (writer *xlsxWriter) writeNumber(i int, number string) {
+	_, _ = writer.sheet.WriteString(`<c r="` + cellReference(i, writer.row) + `"`)
+	if writer.styles[i] > 0 {
+		_, _ = writer.sheet.WriteString(` s="` + strconv.Itoa(writer.styles[i]) + `"`)
+	}
+	_, _ = writer.sheet.WriteString(`><v>` + number + `</v></c>`)
+}
+
+func (writer *xlsxWriter) writeString(i int, value string) {
+	if value == "" {
+		return
+	}
+
+	_, _ = writer.sheet.WriteString(`<c r="` + cellReference(i, writer.row) + `" t="inlineStr"><is><t xml:space="preserve">`)
+	_ = xml.EscapeText(writer.sheet, []byte(value))
+	_, _ = writer.sheet.WriteString(`</t></is></c>`)
+}
+
+func (writer *xlsxWriter) close() error {
+	if _, err := writer.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	if err := writer.sheet.Flush(); err != nil {
+		return err
+	}
+
+	var sheetName strings.Builder
+	_ = xml.EscapeText(&sheetName, []byte(writer.sheetName))
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<workbook xmlns="` + xlsxMainNamespace + `" xmlns:r="` + xlsxRelsNamespace + `"><sheets>` +
+			`<sheet name="` + sheetName.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/styles.xml", writer.stylesXML()},
+	}
+
+	for _, file := range files {
+		entry, err := writer.archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(entry, file.content); err != nil {
+			return err
+		}
+	}
+
+	return writer.archive.Close()
+}
+
+func (writer *xlsxWriter) stylesXML() string {
+	var styles strings.Builder
+	styles.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	styles.WriteString(`<styleSheet xmlns="` + xlsxMainNamespace + `">`)
+
+	if len(writer.formats) > 0 {
+		styles.WriteString(`<numFmts count="` + strconv.Itoa(len(writer.formats)) + `">`)
+		for i, format := range writer.formats {
+			styles.WriteString(`<numFmt numFmtId="` + strconv.Itoa(xlsxFirstCustomFormat+i) + `" formatCode="`)
+			_ = xml.EscapeText(&styles, []byte(format))
+			styles.WriteString(`"/>`)
+		}
+		styles.WriteString(`</numFmts>`)
+	}
+
+	styles.WriteString(`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>`)
+	styles.WriteString(`<fills count="2"><fill><patternFill patternType="none"/></fill>` +
+		`<fill><patternFill patternType="gray125"/></fill></fills>`)
+	styles.WriteString(`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`)
+	styles.WriteString(`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+
+	styles.WriteString(`<cellXfs count="` + strconv.Itoa(len(writer.formats)+2) + `">`)
+	styles.WriteString(`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`)
+	styles.WriteString(xlsxCellFormat(xlsxDateTimeFormat))
+	for i := range writer.formats {
+		styles.WriteString(xlsxCellFormat(xlsxFirstCustomFormat + i))
+	}
+	styles.WriteString(`</cellXfs>`)
+
+	styles.WriteString(`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>`)
+	styles.WriteString(`</styleSheet>`)
+	return styles.String()
+}
+
+func xlsxCellFormat(formatID int) string {
+	return `<xf numFmtId="` + strconv.Itoa(formatID) + `" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`
+}
+
+// cellReference returns cell reference like "A1" by zero-based column index and row number
+func cellReference(column, row int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+
+	return name + strconv.Itoa(row)
+}
+
+// excelSerial converts time (its wall clock) to Excel date serial number
+func excelSerial(moment time.Time) float64 {
+	wall := time.Date(moment.Year(), moment.Month(), moment.Day(),
+		moment.Hour(), moment.Minute(), moment.Second(), moment.Nanosecond(), time.UTC)
+
+	return float64(wall.Sub(xlsxEpoch)) / float64(24*time.Hour)
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}